@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"shopflow/application/config"
+)
+
+var errConfigUsage = errors.New("usage: application config print [--redacted]")
+
+// runConfigCommand — подкоманда `application config print [--redacted]`: печатает итоговую
+// конфигурацию (значения по умолчанию + CONFIG_FILE + окружение) и ошибки её проверки
+func runConfigCommand(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errConfigUsage
+	}
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	redacted := fs.Bool("redacted", false, "hide secrets (passwords, keys, URLs with credentials)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	validationErr := cfg.Validate()
+	if *redacted {
+		cfg = cfg.Redacted()
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		return err
+	}
+	if validationErr != nil {
+		return fmt.Errorf("invalid configuration:\n%w", validationErr)
+	}
+	return nil
+}
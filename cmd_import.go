@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"shopflow/application/importer"
+	"shopflow/application/models"
+	"shopflow/application/publisher"
+	"shopflow/application/repository"
+	"shopflow/application/services"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// runImportCommand — подкоманда `application import`: загрузка заявок из файла напрямую в БД.
+// Отчёт печатается в stdout в JSON; при ошибках в строках команда завершается с ошибкой.
+func runImportCommand(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("file", "-", "path to CSV/NDJSON file, - for stdin")
+	format := fs.String("format", "", "csv or ndjson (default: by file extension, csv for stdin)")
+	opts := models.ImportOptions{}
+	fs.BoolVar(&opts.PreserveTimestamps, "preserve-timestamps", false, "keep created_at/updated_at from the file")
+	fs.BoolVar(&opts.PreserveStatus, "preserve-status", false, "keep status from the file instead of 'new'")
+	fs.BoolVar(&opts.SuppressEvents, "suppress-events", false, "do not publish application_created events")
+	fs.BoolVar(&opts.SkipInvalid, "skip-invalid", false, "import valid rows even if some rows are invalid")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "only validate the file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts.Format = *format
+	if opts.Format == "" {
+		opts.Format = importer.FormatCSV
+		if ext := strings.ToLower(filepath.Ext(*file)); ext == ".ndjson" || ext == ".jsonl" {
+			opts.Format = importer.FormatNDJSON
+		}
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	cfg, err := loadCommandConfig()
+	if err != nil {
+		return err
+	}
+	db, err := openDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// RabbitMQ нужен только если события не подавлены
+	var appPublisher *publisher.ApplicationPublisher
+	if !opts.SuppressEvents && !opts.DryRun {
+		conn, err := amqp.Dial(cfg.RabbitMQ.URL)
+		if err != nil {
+			return fmt.Errorf("failed to connect to RabbitMQ (use -suppress-events to import without it): %w", err)
+		}
+		defer conn.Close()
+		appPublisher = publisher.NewApplicationPublisher(conn)
+		appPublisher.PublishTimeout = cfg.Timeouts.Publish
+	}
+
+	appService := services.NewApplicationService(repository.NewApplicationRepository(db), appPublisher)
+	report, err := appService.ImportApplications(context.Background(), in, opts)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	if report.Failed > 0 && !opts.SkipInvalid {
+		return fmt.Errorf("%d invalid rows, nothing imported", report.Failed)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopflow/application/migrate"
+	"shopflow/application/migrations"
+	"strconv"
+)
+
+var errMigrateUsage = errors.New("usage: application migrate up | down [N] | to <version> | status | force <version>")
+
+// runMigrateCommand — подкоманда `application migrate`: управление схемой БД
+func runMigrateCommand(args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	cfg, err := loadCommandConfig()
+	if err != nil {
+		return err
+	}
+	db, err := openDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	version := func() (uint, error) {
+		if len(args) != 2 {
+			return 0, errMigrateUsage
+		}
+		v, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid version %q", args[1])
+		}
+		return uint(v), nil
+	}
+
+	switch args[0] {
+	case "up":
+		err = m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		err = m.Down(ctx, steps)
+	case "to":
+		var v uint
+		if v, err = version(); err == nil {
+			err = m.To(ctx, v)
+		}
+	case "force":
+		var v uint
+		if v, err = version(); err == nil {
+			err = m.Force(ctx, v)
+		}
+	case "status":
+	default:
+		return errMigrateUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	st, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("version: %d (latest %d)\n", st.Current, st.Latest)
+	if st.Dirty {
+		fmt.Println("dirty: true")
+	}
+	fmt.Printf("pending: %v\n", st.Pending)
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue — чем заменяются секреты при печати
+const redactedValue = "[REDACTED]"
+
+// Redacted — копия конфигурации, в которой заданные секреты заменены на [REDACTED]
+func (c Config) Redacted() Config {
+	walk(reflect.ValueOf(&c).Elem(), func(f reflect.Value, field reflect.StructField) error {
+		if field.Tag.Get("secret") == "true" && f.Kind() == reflect.String && f.String() != "" {
+			f.SetString(redactedValue)
+		}
+		return nil
+	})
+	return c
+}
+
+// Print выводит конфигурацию в YAML в формате, пригодном для CONFIG_FILE
+// (длительности — строками вида "24h0m0s", поля — в порядке объявления)
+func (c Config) Print(w io.Writer) error {
+	root, err := yamlNode(reflect.ValueOf(c))
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func yamlNode(v reflect.Value) (*yaml.Node, error) {
+	if v.Type() == durationType {
+		d := time.Duration(v.Int())
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: d.String()}, nil
+	}
+	if v.Kind() != reflect.Struct {
+		n := &yaml.Node{}
+		if err := n.Encode(v.Interface()); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+
+	m := &yaml.Node{Kind: yaml.MappingNode}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		value, err := yamlNode(v.Field(i))
+		if err != nil {
+			return nil, err
+		}
+		m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, value)
+	}
+	return m, nil
+}
@@ -0,0 +1,193 @@
+// Package export — потоковая выгрузка заявок в CSV, XLSX и NDJSON.
+// Писатели не накапливают строки в памяти: каждая строка сразу уходит в io.Writer.
+package export
+
+import (
+	"fmt"
+	"io"
+	"shopflow/application/models"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // часовые пояса для tz= доступны и в образе без tzdata
+)
+
+// Поддерживаемые форматы выгрузки
+const (
+	FormatCSV    = "csv"
+	FormatXLSX   = "xlsx"
+	FormatNDJSON = "ndjson"
+)
+
+// Columns — все доступные колонки в порядке по умолчанию
+var Columns = []string{"id", "user_id", "text", "file_url", "status", "version", "created_at", "updated_at", "assignee_id"}
+
+// dateLayouts — формат даты и времени для локали (язык или язык-регион)
+var dateLayouts = map[string]string{
+	"ru":    "02.01.2006 15:04:05",
+	"uk":    "02.01.2006 15:04:05",
+	"de":    "02.01.2006 15:04:05",
+	"fr":    "02/01/2006 15:04:05",
+	"es":    "02/01/2006 15:04:05",
+	"it":    "02/01/2006 15:04:05",
+	"en":    "01/02/2006 03:04:05 PM",
+	"en-us": "01/02/2006 03:04:05 PM",
+	"en-gb": "02/01/2006 15:04:05",
+	"ja":    "2006/01/02 15:04:05",
+	"zh":    "2006-01-02 15:04:05",
+}
+
+// RowWriter — писатель одного формата выгрузки
+type RowWriter interface {
+	WriteHeader(columns []string) error
+	// WriteRow пишет одну строку; values соответствуют колонкам заголовка
+	WriteRow(values []any) error
+	// Close дописывает хвост формата (для XLSX — завершает архив) и сбрасывает буферы
+	Close() error
+}
+
+// NewRowWriter создаёт писателя для формата
+func NewRowWriter(format string, w io.Writer) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatXLSX:
+		return newXLSXWriter(w), nil
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// ContentType — MIME-тип формата
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/octet-stream"
+}
+
+// Validate проверяет формат и опции выгрузки, не создавая писателя
+func Validate(opts models.ExportOptions) error {
+	switch opts.Format {
+	case FormatCSV, FormatXLSX, FormatNDJSON:
+	default:
+		return fmt.Errorf("unsupported export format %q", opts.Format)
+	}
+	_, err := NewFormatter(opts)
+	return err
+}
+
+// SupportsLocale — есть ли формат даты для локали
+func SupportsLocale(locale string) bool {
+	_, ok := dateLayoutFor(locale)
+	return ok
+}
+
+// Formatter превращает заявку в значения выбранных колонок
+type Formatter struct {
+	columns    []string
+	dateLayout string
+	location   *time.Location
+}
+
+// NewFormatter проверяет опции выгрузки и создаёт форматтер
+func NewFormatter(opts models.ExportOptions) (*Formatter, error) {
+	f := &Formatter{columns: Columns, dateLayout: time.RFC3339, location: time.UTC}
+
+	if len(opts.Columns) > 0 {
+		known := make(map[string]bool, len(Columns))
+		for _, col := range Columns {
+			known[col] = true
+		}
+		for _, col := range opts.Columns {
+			if !known[col] {
+				return nil, fmt.Errorf("unknown column %q", col)
+			}
+		}
+		f.columns = opts.Columns
+	}
+
+	if opts.Locale != "" {
+		layout, ok := dateLayoutFor(opts.Locale)
+		if !ok {
+			return nil, fmt.Errorf("unsupported locale %q", opts.Locale)
+		}
+		f.dateLayout = layout
+	}
+
+	if opts.Timezone != "" {
+		loc, err := time.LoadLocation(opts.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", opts.Timezone)
+		}
+		f.location = loc
+	}
+	return f, nil
+}
+
+func dateLayoutFor(locale string) (string, bool) {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if layout, ok := dateLayouts[locale]; ok {
+		return layout, true
+	}
+	lang, _, _ := strings.Cut(locale, "-")
+	layout, ok := dateLayouts[lang]
+	return layout, ok
+}
+
+// Columns — выбранные колонки
+func (f *Formatter) Columns() []string {
+	return f.columns
+}
+
+// Values — значения выбранных колонок заявки
+func (f *Formatter) Values(app *models.Application) []any {
+	values := make([]any, len(f.columns))
+	for i, col := range f.columns {
+		switch col {
+		case "id":
+			values[i] = app.ID
+		case "user_id":
+			values[i] = app.UserID
+		case "text":
+			values[i] = app.Text
+		case "file_url":
+			values[i] = app.FileURL
+		case "status":
+			values[i] = app.Status
+		case "version":
+			values[i] = app.Version
+		case "created_at":
+			values[i] = app.CreatedAt.In(f.location).Format(f.dateLayout)
+		case "updated_at":
+			values[i] = app.UpdatedAt.In(f.location).Format(f.dateLayout)
+		case "assignee_id":
+			// nil — не назначена: пустая ячейка в CSV/XLSX, null в NDJSON
+			if app.AssigneeID != nil {
+				values[i] = *app.AssigneeID
+			}
+		}
+	}
+	return values
+}
+
+// formatValue — текстовое представление значения ячейки
+func formatValue(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case uint:
+		return strconv.FormatUint(uint64(x), 10)
+	case int:
+		return strconv.Itoa(x)
+	}
+	return fmt.Sprint(v)
+}
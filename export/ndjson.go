@@ -0,0 +1,37 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+type ndjsonWriter struct {
+	buf     *bufio.Writer
+	enc     *json.Encoder
+	columns []string
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	buf := bufio.NewWriter(w)
+	return &ndjsonWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+// WriteHeader запоминает колонки: в NDJSON они становятся ключами объектов
+func (n *ndjsonWriter) WriteHeader(columns []string) error {
+	n.columns = columns
+	return nil
+}
+
+func (n *ndjsonWriter) WriteRow(values []any) error {
+	row := make(map[string]any, len(values))
+	for i, v := range values {
+		row[n.columns[i]] = v
+	}
+	// Encode сам добавляет перевод строки
+	return n.enc.Encode(row)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.buf.Flush()
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"shopflow/application/importer"
+	"shopflow/application/models"
+	"shopflow/application/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	AppSvc *services.ApplicationService
+	Jobs   *services.JobService
+}
+
+// BulkChangeStatus godoc
+// @Summary Bulk status change by filter
+// @Description Changes status of every application matching the list filters. With dry_run=true returns the count
+// @Description and sample IDs, otherwise starts a background job and returns it.
+// @Security BearerAuth
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param dry_run query bool false "Only count matching applications"
+// @Param user_id query int false "Filter by user ID"
+// @Param status query string false "Filter by status"
+// @Param older_than_days query int false "Only applications created more than N days ago"
+// @Param created_before query string false "Only applications created before this time (RFC 3339)"
+// @Param created_after query string false "Only applications created at or after this time (RFC 3339)"
+// @Param input body models.BulkStatusChangeRequest true "Target status"
+// @Success 200 {object} models.BulkStatusChangePreview "Dry run"
+// @Success 202 {object} models.Job "Background job started"
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/admin/applications/bulk-status [post]
+func (h *AdminHandler) BulkChangeStatus(c *gin.Context) {
+	filter, err := parseApplicationFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Защита от случайного изменения всех заявок сразу
+	if filter == (models.ApplicationFilter{}) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one filter is required"})
+		return
+	}
+
+	var req models.BulkStatusChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	if dryRun {
+		preview, err := h.Jobs.PreviewBulkStatusChange(c.Request.Context(), filter, req.TargetStatus)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, preview)
+		return
+	}
+
+	job, err := h.Jobs.StartBulkStatusChange(c.Request.Context(), c.GetUint("user_id"), filter, req.TargetStatus)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/api/jobs/%d", job.ID))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ImportApplications godoc
+// @Summary Bulk import Applications
+// @Description Loads applications from CSV (with header) or NDJSON. Columns: user_id, text, file_url, status, created_at, updated_at.
+// @Description The file is sent as multipart field "file" or as the raw request body. Rows are validated and reported per line;
+// @Description if any row is invalid nothing is imported unless skip_invalid=true.
+// @Security BearerAuth
+// @Tags Admin
+// @Accept text/csv,application/x-ndjson,multipart/form-data
+// @Produce json
+// @Param format query string false "csv or ndjson (defaults to file extension or Content-Type)"
+// @Param preserve_timestamps query bool false "Keep created_at/updated_at from the file"
+// @Param preserve_status query bool false "Keep status from the file instead of 'new'"
+// @Param suppress_events query bool false "Do not publish application_created events"
+// @Param skip_invalid query bool false "Import valid rows even if some rows are invalid"
+// @Param dry_run query bool false "Only validate the file"
+// @Param file formData file false "File to import"
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} models.ImportReport "File contains invalid rows"
+// @Router /api/admin/applications/import [post]
+func (h *AdminHandler) ImportApplications(c *gin.Context) {
+	body := c.Request.Body
+	fileName := ""
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field \"file\" is required"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		body, fileName = f, fh.Filename
+	}
+
+	opts := models.ImportOptions{Format: importFormat(c.Query("format"), fileName, c.ContentType())}
+	opts.PreserveTimestamps, _ = strconv.ParseBool(c.Query("preserve_timestamps"))
+	opts.PreserveStatus, _ = strconv.ParseBool(c.Query("preserve_status"))
+	opts.SuppressEvents, _ = strconv.ParseBool(c.Query("suppress_events"))
+	opts.SkipInvalid, _ = strconv.ParseBool(c.Query("skip_invalid"))
+	opts.DryRun, _ = strconv.ParseBool(c.Query("dry_run"))
+
+	report, err := h.AppSvc.ImportApplications(c.Request.Context(), body, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if report.Failed > 0 && !opts.SkipInvalid {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// importFormat определяет формат загрузки: явный параметр, расширение файла или Content-Type
+func importFormat(explicit, fileName, contentType string) string {
+	if explicit != "" {
+		return explicit
+	}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return importer.FormatCSV
+	case ".ndjson", ".jsonl":
+		return importer.FormatNDJSON
+	}
+	switch contentType {
+	case "application/x-ndjson", "application/jsonl":
+		return importer.FormatNDJSON
+	}
+	return importer.FormatCSV
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"shopflow/application/models"
+	"shopflow/application/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	Keys *services.APIKeyService
+}
+
+func apiKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+	case errors.Is(err, services.ErrInvalidAPIKey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary Issue API key
+// @Description Issues a key for service-to-service calls, sent in header X-API-Key instead of a JWT.
+// @Description Requests run as user_id/email of the key. Scopes: applications:read, applications:write,
+// @Description applications:all (applications of all users, like staff), admin. The key is returned only here.
+// @Security BearerAuth
+// @Tags API keys
+// @Accept json
+// @Produce json
+// @Param input body models.CreateAPIKeyRequest true "Key"
+// @Success 201 {object} models.APIKey
+// @Failure 400 {object} map[string]string
+// @Router /api/admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.Keys.CreateKey(c.Request.Context(), c.GetUint("user_id"), req)
+	if err != nil {
+		apiKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description All keys including revoked and expired ones, without the key itself
+// @Security BearerAuth
+// @Tags API keys
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Router /api/admin/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.Keys.ListKeys(c.Request.Context())
+	if err != nil {
+		apiKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Other replicas stop accepting the key within the API key cache TTL
+// @Security BearerAuth
+// @Tags API keys
+// @Param id path int true "Key ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} map[string]string
+// @Router /api/admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+	if err := h.Keys.RevokeKey(c.Request.Context(), uint(id)); err != nil {
+		apiKeyError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
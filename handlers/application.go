@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
+	"shopflow/application/logging"
+	"shopflow/application/middleware"
 	"shopflow/application/models"
+	"shopflow/application/repository"
 	"shopflow/application/services"
 	"strconv"
 
@@ -15,6 +19,12 @@ import (
 type BaseHandler struct {
 	AppSvc    *services.ApplicationService
 	Publisher *services.NotificationService
+	Jobs      *services.JobService
+
+	// RequireIfMatch — отвечать 428 на PATCH/DELETE без заголовка If-Match
+	RequireIfMatch bool
+	// BatchMaxOperations — максимальное число операций в POST /api/applications:batch
+	BatchMaxOperations int
 }
 
 type ApplicationHandler struct {
@@ -24,12 +34,16 @@ type ApplicationHandler struct {
 // CreateApplication godoc
 // @Summary Create Application
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Tags UserApplication
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
 // @Param input body models.CreateApplicationRequest true "Create Application"
 // @Success 201 {object} models.Application "Application successfully created"
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]string
 // @Router /api/applications [post]
 func (h *ApplicationHandler) CreateApplication(c *gin.Context) {
 	var req models.CreateApplicationRequest
@@ -39,16 +53,16 @@ func (h *ApplicationHandler) CreateApplication(c *gin.Context) {
 	}
 
 	userID := c.GetUint("user_id")
-	token := c.GetString("Authorization")
 	email := c.GetString("email")
 
 	ctx := c.Request.Context()
 
-	app, err := h.AppSvc.CreateApplication(ctx, userID, token, req.Text, req.FileURL, req.Status)
+	app, err := h.AppSvc.CreateApplication(ctx, userID, req.Text, req.FileURL, req.Status)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	withApplicationID(c, app.ID)
 
 	// Формируем сообщение для уведомления
 	msg := services.ApplicationCreatedMessage{
@@ -56,15 +70,17 @@ func (h *ApplicationHandler) CreateApplication(c *gin.Context) {
 		UserID: app.UserID,
 		Text:   app.Text,
 		File:   app.FileURL,
+		Status: app.Status,
 		Email:  email,
 	}
 
-	// Публикуем асинхронно
-	go func(m services.ApplicationCreatedMessage) {
-		if err := h.Publisher.PublishApplicationCreated(m); err != nil {
-			log.Println("[ERROR] failed to publish application_created event:", err)
+	// Публикуем асинхронно: ответ уже уйдёт, поэтому отмена запроса не должна прерывать публикацию
+	publishCtx := context.WithoutCancel(c.Request.Context())
+	h.Publisher.Go(func() {
+		if err := h.Publisher.PublishApplicationCreated(publishCtx, msg); err != nil {
+			slog.ErrorContext(publishCtx, "failed to publish application_created event", logging.Err(err))
 		}
-	}(msg)
+	})
 
 	c.JSON(http.StatusCreated, app)
 }
@@ -72,28 +88,37 @@ func (h *ApplicationHandler) CreateApplication(c *gin.Context) {
 // GetApplications godoc
 // @Summary Gets all the Applications
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Tags UserApplication
 // @Accept json
 // @Produce json
 // @Param user_id query int false "Filter by user ID"
+// @Param status query string false "Filter by status"
+// @Param assignee_id query int false "Filter by assignee"
+// @Param older_than_days query int false "Only applications created more than N days ago"
+// @Param created_before query string false "Only applications created before this time (RFC 3339)"
+// @Param created_after query string false "Only applications created at or after this time (RFC 3339)"
+// @Param If-None-Match header string false "ETag of a previously received list"
 // @Success 200 {array} models.Application
+// @Success 304 "Not Modified"
 // @Failure 400 {object} map[string]string
 // @Router /api/applications [get]
 func (h *ApplicationHandler) GetApplications(c *gin.Context) {
-	userIDStr := c.Query("user_id")
-	var userID uint = 0
-	if userIDStr != "" {
-		if uid, err := strconv.ParseUint(userIDStr, 10, 32); err == nil {
-			userID = uint(uid)
-		}
+	filter, err := parseApplicationFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	apps, err := h.AppSvc.GetAll(userID)
+	apps, err := h.AppSvc.GetAll(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if notModified(c, applicationsETag(apps)) {
+		return
+	}
 	c.JSON(http.StatusOK, apps)
 }
 
@@ -101,10 +126,13 @@ func (h *ApplicationHandler) GetApplications(c *gin.Context) {
 // @Summary Gets Application by ID
 // @Tags UserApplication
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "Application ID"
+// @Param If-None-Match header string false "ETag of a previously received application"
 // @Success 200 {object} models.Application "Данные одной заявки"
+// @Success 304 "Not Modified"
 // @Failure 400 {object} map[string]string
 // @Router /api/applications/{id} [get]
 func (h *ApplicationHandler) GetApplicationById(c *gin.Context) {
@@ -114,8 +142,9 @@ func (h *ApplicationHandler) GetApplicationById(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
 		return
 	}
+	withApplicationID(c, uint(id))
 
-	app, err := h.AppSvc.GetApplicationById(uint(id))
+	app, err := h.AppSvc.GetApplicationById(c.Request.Context(), uint(id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
@@ -125,6 +154,9 @@ func (h *ApplicationHandler) GetApplicationById(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, applicationETag(app)) {
+		return
+	}
 	c.JSON(http.StatusOK, app)
 }
 
@@ -132,12 +164,16 @@ func (h *ApplicationHandler) GetApplicationById(c *gin.Context) {
 // @Summary Delete Application by ID
 // @Tags UserApplication
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "Application ID"
+// @Param If-Match header string false "ETag of the application being deleted"
 // @Success 204 "No Content"
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Router /api/applications/{id} [delete]
 func (h *ApplicationHandler) DeleteApplication(c *gin.Context) {
 	idStr := c.Param("id")
@@ -146,30 +182,47 @@ func (h *ApplicationHandler) DeleteApplication(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
 		return
 	}
+	withApplicationID(c, uint(id))
 
-	err = h.AppSvc.DeleteApplication(uint(id))
+	version, mustExist, ok := h.expectedVersion(c)
+	if !ok {
+		return
+	}
+
+	app, err := h.AppSvc.DeleteApplication(c.Request.Context(), uint(id), version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "application not found"})
+			applicationNotFound(c, mustExist)
+			return
+		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "application version mismatch"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	h.publishEvent(c.Request.Context(), services.RoutingKeyApplicationDeleted, app)
 	c.Status(http.StatusNoContent)
 }
 
 // UpdateApplication godoc
 // @Summary Update Application
+// @Description Partial update: only the supplied fields are changed, the full record is returned
 // @Tags UserApplication
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "Application ID"
+// @Param If-Match header string false "ETag of the application being updated"
 // @Param input body models.UpdateApplicationRequest true "Application request with text, fileURL и status"
 // @Success 200 {object} models.Application
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Router /api/applications/{id} [patch]
 func (h *ApplicationHandler) UpdateApplication(c *gin.Context) {
 	idStr := c.Param("id")
@@ -178,22 +231,63 @@ func (h *ApplicationHandler) UpdateApplication(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
 		return
 	}
+	withApplicationID(c, uint(id))
+
+	version, mustExist, ok := h.expectedVersion(c)
+	if !ok {
+		return
+	}
 
 	var req models.UpdateApplicationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		return
+	}
+	if req.AssigneeID != nil && !middleware.IsStaff(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only staff can assign applications"})
+		return
+	}
 
-	app, err := h.AppSvc.UpdateApplication(req, uint(id))
+	app, err := h.AppSvc.UpdateApplication(c.Request.Context(), req, uint(id), version)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			applicationNotFound(c, mustExist)
+			return
+		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "application version mismatch"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	if app == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
-		return
+
+	routingKey := services.RoutingKeyApplicationUpdated
+	if req.Status != nil && req.Text == nil && req.FileURL == nil && req.AssigneeID == nil {
+		routingKey = services.RoutingKeyApplicationStatusChanged
 	}
+	h.publishEvent(c.Request.Context(), routingKey, app)
 
+	c.Header("ETag", applicationETag(app))
 	c.JSON(http.StatusOK, app)
 }
+
+// publishEvent асинхронно публикует событие об изменении заявки в контексте трассировки запроса
+func (h *ApplicationHandler) publishEvent(ctx context.Context, routingKey string, app *models.Application) {
+	msg := applicationEvent(app)
+	ctx = context.WithoutCancel(ctx)
+	h.Publisher.Go(func() {
+		if err := h.Publisher.PublishApplicationEvent(ctx, routingKey, msg); err != nil {
+			slog.ErrorContext(ctx, "failed to publish event", "routing_key", routingKey, "application_id", msg.ID, logging.Err(err))
+		}
+	})
+}
+
+// withApplicationID добавляет ID заявки в контекст запроса, чтобы он попал в логи
+func withApplicationID(c *gin.Context, id uint) {
+	c.Request = c.Request.WithContext(logging.WithApplicationID(c.Request.Context(), id))
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"shopflow/application/logging"
+	"shopflow/application/middleware"
+	"shopflow/application/models"
+	"shopflow/application/repository"
+	"shopflow/application/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultBatchMaxOperations — лимит операций в пакете, если он не задан в конфигурации
+const DefaultBatchMaxOperations = 500
+
+// ApplicationsAction — диспетчер «кастомных методов» коллекции вида /api/applications:<action>.
+// Gin не поддерживает экранирование ':' в пути, поэтому суффикс приходит параметром.
+func (h *ApplicationHandler) ApplicationsAction(c *gin.Context) {
+	switch c.Param("action") {
+	case ":batch":
+		h.BatchApplications(c)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown action"})
+	}
+}
+
+// BatchApplications godoc
+// @Summary Batch operations on Applications
+// @Description Executes create/update/transition/delete operations in one request.
+// @Description mode=atomic (default) runs everything in one transaction, mode=independent reports a result per operation.
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Tags UserApplication
+// @Accept json
+// @Produce json
+// @Param input body models.BatchRequest true "Batch operations"
+// @Success 200 {object} models.BatchResponse
+// @Success 207 {object} models.BatchResponse "Some operations failed (independent mode)"
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} models.BatchResponse "Batch rolled back (atomic mode)"
+// @Failure 413 {object} map[string]string
+// @Router /api/applications:batch [post]
+func (h *ApplicationHandler) BatchApplications(c *gin.Context) {
+	var req models.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := h.BatchMaxOperations
+	if limit <= 0 {
+		limit = DefaultBatchMaxOperations
+	}
+	if len(req.Operations) > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("batch is limited to %d operations", limit)})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = models.BatchModeAtomic
+	}
+
+	userID := c.GetUint("user_id")
+	email := c.GetString("email")
+
+	items, err := h.AppSvc.ExecuteBatch(c.Request.Context(), userID, middleware.IsStaff(c.GetString("role")), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := models.BatchResponse{Mode: req.Mode, Results: make([]models.BatchResult, len(items))}
+	for i, item := range items {
+		result := models.BatchResult{
+			Index:       i,
+			Op:          item.Op,
+			Status:      batchItemStatus(item),
+			Application: item.App,
+		}
+		if item.Err != nil {
+			result.Error = item.Err.Error()
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+		resp.Results[i] = result
+	}
+
+	// События публикуем только для реально применённых операций, одно на заявку
+	publishCtx := context.WithoutCancel(c.Request.Context())
+	h.Publisher.Go(func() { h.publishBatchEvents(publishCtx, items, email) })
+
+	switch {
+	case resp.Failed == 0:
+		c.JSON(http.StatusOK, resp)
+	case req.Mode == models.BatchModeAtomic:
+		c.JSON(http.StatusConflict, resp)
+	default:
+		c.JSON(http.StatusMultiStatus, resp)
+	}
+}
+
+func batchItemStatus(item services.BatchItemResult) int {
+	switch {
+	case item.Err == nil:
+		switch item.Op {
+		case models.BatchOpCreate:
+			return http.StatusCreated
+		case models.BatchOpDelete:
+			return http.StatusNoContent
+		}
+		return http.StatusOK
+	case errors.Is(item.Err, services.ErrInvalidOperation):
+		return http.StatusBadRequest
+	case errors.Is(item.Err, services.ErrBatchForbidden):
+		return http.StatusForbidden
+	case errors.Is(item.Err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(item.Err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(item.Err, services.ErrBatchRolledBack):
+		return http.StatusConflict
+	case errors.Is(item.Err, services.ErrBatchSkipped):
+		return http.StatusFailedDependency
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *ApplicationHandler) publishBatchEvents(ctx context.Context, items []services.BatchItemResult, email string) {
+	for _, item := range items {
+		if item.Err != nil || item.App == nil {
+			continue
+		}
+		app := item.App
+
+		var err error
+		switch item.Op {
+		case models.BatchOpCreate:
+			err = h.Publisher.PublishApplicationCreated(ctx, services.ApplicationCreatedMessage{
+				ID:     app.ID,
+				UserID: app.UserID,
+				Text:   app.Text,
+				File:   app.FileURL,
+				Status: app.Status,
+				Email:  email,
+			})
+		case models.BatchOpUpdate:
+			err = h.Publisher.PublishApplicationEvent(ctx, services.RoutingKeyApplicationUpdated, applicationEvent(app))
+		case models.BatchOpTransition:
+			err = h.Publisher.PublishApplicationEvent(ctx, services.RoutingKeyApplicationStatusChanged, applicationEvent(app))
+		case models.BatchOpDelete:
+			err = h.Publisher.PublishApplicationEvent(ctx, services.RoutingKeyApplicationDeleted, applicationEvent(app))
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to publish batch event", "op", item.Op, "application_id", app.ID, logging.Err(err))
+		}
+	}
+}
+
+func applicationEvent(app *models.Application) services.ApplicationEventMessage {
+	msg := services.ApplicationEventMessage{
+		ID:         app.ID,
+		UserID:     app.UserID,
+		Status:     app.Status,
+		Version:    app.Version,
+		AssigneeID: derefUint(app.AssigneeID),
+	}
+	msg.SetPrevious(app)
+	return msg
+}
+
+func derefUint(v *uint) uint {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"shopflow/application/logging"
+	"shopflow/application/models"
+	"shopflow/application/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Таймауты WebSocket-соединения: сервер пингует клиента каждые wsPingInterval
+// и закрывает соединение, если pong не пришёл за wsPongWait.
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingInterval   = 50 * time.Second
+	wsMaxMessageSize = 4096
+)
+
+type DashboardHandler struct {
+	Hub *services.DashboardHub
+	// AllowedOrigins — разрешённые Origin браузерных клиентов; пусто — только тот же хост
+	AllowedOrigins []string
+	// Shutdown закрывается при остановке сервиса: клиенты получают 1001 Going Away
+	Shutdown <-chan struct{}
+}
+
+func (h *DashboardHandler) upgrader() *websocket.Upgrader {
+	u := &websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}
+	if len(h.AllowedOrigins) > 0 {
+		u.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			for _, o := range h.AllowedOrigins {
+				if o == "*" || o == origin {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return u
+}
+
+// Dashboard godoc
+// @Summary Back-office live dashboard (WebSocket)
+// @Description WebSocket for staff. Client messages: {"type":"subscribe"|"unsubscribe","topics":[...]},
+// @Description {"type":"view"|"leave","application_id":N}, {"type":"ping"}. Topics: "all", "status:<status>",
+// @Description "assignee:<id>", "assignee:me", "unassigned". Server messages: "event" (application event),
+// @Description "presence" (staff currently viewing the application; no viewers field means nobody), "subscribed", "pong", "error".
+// @Description Browsers cannot set headers on WebSocket, so the token may be passed as access_token query parameter.
+// @Security BearerAuth
+// @Tags Dashboard
+// @Param access_token query string false "JWT for clients that cannot set Authorization header"
+// @Success 101 "Switching Protocols"
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/dashboard/ws [get]
+func (h *DashboardHandler) Dashboard(c *gin.Context) {
+	conn, err := h.upgrader().Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade уже ответил клиенту
+		return
+	}
+	defer conn.Close()
+
+	sess, err := h.Hub.Open(c.GetUint("user_id"), c.GetString("email"))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to open session", "component", "dashboard", logging.Err(err))
+		return
+	}
+	defer h.Hub.Close(c.Request.Context(), sess)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.readLoop(c.Request.Context(), conn, sess, stopped)
+	}()
+	h.writeLoop(conn, sess, done)
+	close(stopped)
+	conn.Close()
+	<-done
+}
+
+// readLoop разбирает сообщения клиента; ответы уходят через sess.Out,
+// потому что писать в соединение может только writeLoop
+func (h *DashboardHandler) readLoop(ctx context.Context, conn *websocket.Conn, sess *services.DashboardSession, stopped <-chan struct{}) {
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var reply *models.DashboardServerMessage
+		var msg models.DashboardClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			reply = &models.DashboardServerMessage{Type: models.DashboardError, Error: "invalid message"}
+		} else {
+			reply = h.handleMessage(ctx, sess, msg)
+		}
+		if reply == nil {
+			continue
+		}
+		select {
+		case sess.Out <- *reply:
+		case <-stopped:
+			return
+		}
+	}
+}
+
+// handleMessage выполняет команду клиента; nil — ответ не нужен
+func (h *DashboardHandler) handleMessage(ctx context.Context, sess *services.DashboardSession, msg models.DashboardClientMessage) *models.DashboardServerMessage {
+	fail := func(text string) *models.DashboardServerMessage {
+		return &models.DashboardServerMessage{Type: models.DashboardError, Error: text}
+	}
+
+	switch msg.Type {
+	case models.DashboardSubscribe:
+		topics, err := sess.Subscribe(msg.Topics)
+		if err != nil {
+			return fail(err.Error())
+		}
+		return &models.DashboardServerMessage{Type: models.DashboardSubscribed, Topics: topics}
+	case models.DashboardUnsubscribe:
+		return &models.DashboardServerMessage{Type: models.DashboardSubscribed, Topics: sess.Unsubscribe(msg.Topics)}
+	case models.DashboardView, models.DashboardLeave:
+		if msg.ApplicationID == 0 {
+			return fail("application_id is required")
+		}
+		action := h.Hub.View
+		if msg.Type == models.DashboardLeave {
+			action = h.Hub.Leave
+		}
+		if err := action(ctx, sess, msg.ApplicationID); err != nil {
+			slog.ErrorContext(ctx, "failed to update presence", "component", "dashboard",
+				"session_id", sess.ID, "type", msg.Type, "application_id", msg.ApplicationID, logging.Err(err))
+			return fail("presence update failed")
+		}
+		// актуальный список зрителей придёт сообщением presence
+		return nil
+	case models.DashboardPing:
+		return &models.DashboardServerMessage{Type: models.DashboardPong}
+	}
+	return fail("unknown message type")
+}
+
+func (h *DashboardHandler) writeLoop(conn *websocket.Conn, sess *services.DashboardSession, done <-chan struct{}) {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	write := func(msg models.DashboardServerMessage) error {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(msg)
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-h.Shutdown:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down"),
+				time.Now().Add(wsWriteWait))
+			return
+		case msg := <-sess.Out:
+			if err := write(msg); err != nil {
+				return
+			}
+		case e, ok := <-sess.Events.C:
+			if !ok {
+				// клиент не успевал читать события — закрываем, он переподключится
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"),
+					time.Now().Add(wsWriteWait))
+				return
+			}
+			if !sess.Matches(&e) {
+				continue
+			}
+			if err := write(models.DashboardServerMessage{Type: models.DashboardEvent, Event: &e}); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
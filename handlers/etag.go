@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"shopflow/application/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// applicationETag — сильный ETag заявки, построенный по её версии
+func applicationETag(app *models.Application) string {
+	return fmt.Sprintf(`"%d"`, app.Version)
+}
+
+// applicationsETag — слабый ETag списка заявок: хэш от пар id/версия
+func applicationsETag(apps []models.Application) string {
+	h := sha256.New()
+	for _, app := range apps {
+		fmt.Fprintf(h, "%d:%d;", app.ID, app.Version)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil))[:16] + `"`
+}
+
+// notModified выставляет ETag и, если он совпадает с If-None-Match,
+// отвечает 304 и возвращает true.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		// If-None-Match использует слабое сравнение
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// expectedVersion разбирает заголовок If-Match (один ETag или "*") и возвращает
+// ожидаемую версию заявки. 0 означает «без проверки»; mustExist — передан "*",
+// и отсутствующая заявка должна давать 412, а не 404 (RFC 9110, 13.1.1).
+// При ошибке ответ уже отправлен и ok == false.
+func (h *ApplicationHandler) expectedVersion(c *gin.Context) (version int, mustExist bool, ok bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		if h.RequireIfMatch {
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+			return 0, false, false
+		}
+		return 0, false, true
+	}
+	if header == "*" {
+		return 0, true, true
+	}
+
+	if strings.Contains(header, ",") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "If-Match must contain a single entity tag"})
+		return 0, false, false
+	}
+	// If-Match использует сильное сравнение, поэтому слабый ETag никогда не совпадает
+	if strings.HasPrefix(header, "W/") {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "application version mismatch"})
+		return 0, false, false
+	}
+	v, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || v <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid If-Match header"})
+		return 0, false, false
+	}
+	return v, false, true
+}
+
+// applicationNotFound отвечает на отсутствие заявки: 404, а при If-Match: * — 412
+func applicationNotFound(c *gin.Context, mustExist bool) {
+	if mustExist {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "application does not exist"})
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExpectedVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		ifMatch        string
+		requireIfMatch bool
+		wantVersion    int
+		wantMustExist  bool
+		wantOK         bool
+		wantStatus     int // ответ, если ok == false
+	}{
+		{name: "empty", ifMatch: "", wantOK: true},
+		{name: "empty required", ifMatch: "", requireIfMatch: true, wantStatus: http.StatusPreconditionRequired},
+		{name: "wildcard", ifMatch: "*", wantMustExist: true, wantOK: true},
+		{name: "wildcard required", ifMatch: "*", requireIfMatch: true, wantMustExist: true, wantOK: true},
+		{name: "strong etag", ifMatch: `"1"`, wantVersion: 1, wantOK: true},
+		{name: "strong etag with spaces", ifMatch: ` "7" `, wantVersion: 7, wantOK: true},
+		{name: "weak etag never matches", ifMatch: `W/"1"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "not a version", ifMatch: `"x"`, wantStatus: http.StatusBadRequest},
+		{name: "zero version", ifMatch: `"0"`, wantStatus: http.StatusBadRequest},
+		{name: "several etags", ifMatch: `"1", "2"`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPatch, "/api/applications/1", nil)
+			if tt.ifMatch != "" {
+				c.Request.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			h := &ApplicationHandler{BaseHandler: &BaseHandler{RequireIfMatch: tt.requireIfMatch}}
+			version, mustExist, ok := h.expectedVersion(c)
+
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				if w.Code != tt.wantStatus {
+					t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+				}
+				return
+			}
+			if version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", version, tt.wantVersion)
+			}
+			if mustExist != tt.wantMustExist {
+				t.Errorf("mustExist = %v, want %v", mustExist, tt.wantMustExist)
+			}
+			if c.Writer.Written() {
+				t.Errorf("unexpected response %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestApplicationNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		mustExist  bool
+		wantStatus int
+	}{
+		{name: "without If-Match", wantStatus: http.StatusNotFound},
+		{name: "If-Match wildcard", mustExist: true, wantStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			applicationNotFound(c, tt.mustExist)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
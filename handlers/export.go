@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"shopflow/application/export"
+	"shopflow/application/logging"
+	"shopflow/application/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportApplications godoc
+// @Summary Export Applications
+// @Description Streams applications matching the list filters as CSV, XLSX or NDJSON.
+// @Description With async=true starts a background export job instead; the file is then downloaded via /api/jobs/{id}/download.
+// @Description The file and the job are deleted EXPORT_RETENTION (24h by default) after the export finishes.
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Tags UserApplication
+// @Produce octet-stream
+// @Param format query string false "csv (default), xlsx or ndjson"
+// @Param columns query string false "Comma-separated columns: id,user_id,text,file_url,status,version,created_at,updated_at,assignee_id"
+// @Param locale query string false "Date format locale, e.g. ru, en-US, de (defaults to Accept-Language, then RFC 3339)"
+// @Param tz query string false "IANA time zone for dates, e.g. Europe/Moscow (default UTC)"
+// @Param async query bool false "Run as a background job"
+// @Param user_id query int false "Filter by user ID"
+// @Param status query string false "Filter by status"
+// @Param assignee_id query int false "Filter by assignee"
+// @Param older_than_days query int false "Only applications created more than N days ago"
+// @Param created_before query string false "Only applications created before this time (RFC 3339)"
+// @Param created_after query string false "Only applications created at or after this time (RFC 3339)"
+// @Success 200 {file} file
+// @Success 202 {object} models.Job "Background export started"
+// @Failure 400 {object} map[string]string
+// @Router /api/applications/export [get]
+func (h *ApplicationHandler) ExportApplications(c *gin.Context) {
+	filter, err := parseApplicationFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := models.ExportOptions{
+		Format:   c.DefaultQuery("format", export.FormatCSV),
+		Locale:   c.Query("locale"),
+		Timezone: c.Query("tz"),
+	}
+	if cols := c.Query("columns"); cols != "" {
+		for _, col := range strings.Split(cols, ",") {
+			opts.Columns = append(opts.Columns, strings.TrimSpace(col))
+		}
+	}
+	if opts.Locale == "" {
+		// Accept-Language — только подсказка: неизвестную локаль молча игнорируем
+		if locale := preferredLanguage(c.GetHeader("Accept-Language")); export.SupportsLocale(locale) {
+			opts.Locale = locale
+		}
+	}
+	if err := export.Validate(opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if async, _ := strconv.ParseBool(c.Query("async")); async {
+		job, err := h.Jobs.StartExport(c.Request.Context(), c.GetUint("user_id"), filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Location", fmt.Sprintf("/api/jobs/%d", job.ID))
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	fileName := fmt.Sprintf("applications-%s.%s", time.Now().UTC().Format("20060102-150405"), opts.Format)
+	c.Header("Content-Type", export.ContentType(opts.Format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	c.Status(http.StatusOK)
+
+	rows, err := h.AppSvc.ExportApplications(c.Request.Context(), filter, opts, c.Writer)
+	if err != nil {
+		// Заголовки уже отправлены: сообщить об ошибке можно только обрывом ответа
+		slog.ErrorContext(c.Request.Context(), "export failed", "rows", rows, logging.Err(err))
+		c.Abort()
+	}
+}
+
+// preferredLanguage — первый язык из заголовка Accept-Language
+func preferredLanguage(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	tag, _, _ := strings.Cut(first, ";")
+	tag = strings.TrimSpace(tag)
+	if tag == "*" {
+		return ""
+	}
+	return tag
+}
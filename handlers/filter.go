@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"shopflow/application/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseApplicationFilter разбирает общие фильтры списка заявок из query-параметров:
+// user_id, status, assignee_id, older_than_days, created_before и created_after (RFC 3339).
+func parseApplicationFilter(c *gin.Context) (models.ApplicationFilter, error) {
+	var f models.ApplicationFilter
+
+	if v := c.Query("user_id"); v != "" {
+		uid, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return f, fmt.Errorf("invalid user_id: %q", v)
+		}
+		f.UserID = uint(uid)
+	}
+
+	f.Status = c.Query("status")
+
+	if v := c.Query("assignee_id"); v != "" {
+		aid, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return f, fmt.Errorf("invalid assignee_id: %q", v)
+		}
+		f.AssigneeID = uint(aid)
+	}
+
+	if v := c.Query("older_than_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			return f, fmt.Errorf("invalid older_than_days: %q", v)
+		}
+		before := time.Now().AddDate(0, 0, -days)
+		f.CreatedBefore = &before
+	}
+
+	if v := c.Query("created_before"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, fmt.Errorf("invalid created_before: %q", v)
+		}
+		if f.CreatedBefore == nil || t.Before(*f.CreatedBefore) {
+			f.CreatedBefore = &t
+		}
+	}
+
+	if v := c.Query("created_after"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, fmt.Errorf("invalid created_after: %q", v)
+		}
+		f.CreatedAfter = &t
+	}
+
+	return f, nil
+}
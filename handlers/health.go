@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"shopflow/application/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	Checks *health.Registry
+	// Stopping — сервис останавливается и не должен получать новый трафик
+	Stopping func() bool
+}
+
+// Livez godoc
+// @Summary Liveness probe
+// @Description Process is running and able to serve HTTP. Does not check dependencies.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+// Readyz godoc
+// @Summary Readiness probe
+// @Description Checks Postgres, RabbitMQ and the Auth gRPC service (results are cached for a short time).
+// @Description Returns 503 when any check fails or the service is shutting down. Use ?verbose for per-check details.
+// @Tags Health
+// @Produce json
+// @Param verbose query bool false "Include per-check results"
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	_, verbose := c.GetQuery("verbose")
+
+	if h.Stopping != nil && h.Stopping() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": health.StatusFail, "error": "shutting down"})
+		return
+	}
+
+	report := h.Checks.Run(c.Request.Context())
+	code := http.StatusOK
+	if !report.OK() {
+		code = http.StatusServiceUnavailable
+	}
+	if !verbose {
+		report.Checks = nil
+	}
+	c.JSON(code, report)
+}
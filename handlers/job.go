@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"shopflow/application/logging"
+	"shopflow/application/middleware"
+	"shopflow/application/models"
+	"shopflow/application/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobHandler — фоновые задачи: доступны создателю задачи и администраторам
+type JobHandler struct {
+	Jobs *services.JobService
+}
+
+// loadJob находит задачу из пути и проверяет доступ к ней.
+// Чужие задачи выглядят как несуществующие. При ошибке ответ уже отправлен.
+func (h *JobHandler) loadJob(c *gin.Context) (*models.Job, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return nil, false
+	}
+
+	job, err := h.Jobs.GetJob(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if job.CreatedBy != c.GetUint("user_id") && c.GetString("role") != middleware.RoleAdmin {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return nil, false
+	}
+	return job, true
+}
+
+// GetJob godoc
+// @Summary Get background job
+// @Description Returns status and progress of a background job
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Tags Jobs
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 404 {object} map[string]string
+// @Router /api/jobs/{id} [get]
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJob godoc
+// @Summary Cancel background job
+// @Description Requests cancellation of a pending or running job
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Tags Jobs
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 202 {object} models.Job
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/jobs/{id}/cancel [post]
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.Jobs.CancelJob(c.Request.Context(), job.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusConflict, gin.H{"error": "job already finished"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// DownloadJobResult godoc
+// @Summary Download export file
+// @Description Downloads the file produced by a finished background export.
+// @Description Available for EXPORT_RETENTION (24h by default) after the export finishes, then the job returns 404.
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Tags Jobs
+// @Produce octet-stream
+// @Param id path int true "Job ID"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/jobs/{id}/download [get]
+func (h *JobHandler) DownloadJobResult(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.Jobs.ExportResult(job)
+	if err != nil {
+		if errors.Is(err, services.ErrExportNotReady) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "export file not found"})
+		return
+	}
+
+	c.Header("Content-Type", result.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(result.Size, 10))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": result.FileName}))
+	c.Status(http.StatusOK)
+	// заголовки уже отправлены: при ошибке остаётся только оборвать ответ
+	if err := h.Jobs.WriteExportFile(c.Request.Context(), job, c.Writer); err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to send export file", "job_id", job.ID, logging.Err(err))
+		c.Abort()
+	}
+}
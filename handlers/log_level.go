@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"shopflow/application/logging"
+	"shopflow/application/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLogLevel godoc
+// @Summary Current log level
+// @Security BearerAuth
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} models.LogLevel
+// @Failure 403 {object} map[string]string
+// @Router /api/admin/log-level [get]
+func (h *AdminHandler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, models.LogLevel{Level: logging.Level()})
+}
+
+// SetLogLevel godoc
+// @Summary Change log level at runtime
+// @Description Changes the log level of the replica that serves the request, without a restart.
+// @Description The level from the configuration (LOG_LEVEL) is restored on the next start.
+// @Security BearerAuth
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param input body models.LogLevel true "debug, info, warn or error"
+// @Success 200 {object} models.LogLevel
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/admin/log-level [put]
+func (h *AdminHandler) SetLogLevel(c *gin.Context) {
+	var req models.LogLevel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	previous := logging.Level()
+	if err := logging.SetLevel(req.Level); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slog.WarnContext(c.Request.Context(), "log level changed", "from", previous, "to", logging.Level())
+	c.JSON(http.StatusOK, models.LogLevel{Level: logging.Level()})
+}
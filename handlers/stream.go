@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"shopflow/application/middleware"
+	"shopflow/application/models"
+	"shopflow/application/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultSSEHeartbeat — интервал комментариев-пингов, не дающих прокси закрыть простаивающее соединение
+const DefaultSSEHeartbeat = 15 * time.Second
+
+// sseRetry — через сколько миллисекунд браузер переподключается после обрыва
+const sseRetry = 3000
+
+type StreamHandler struct {
+	Events    *services.EventStream
+	Heartbeat time.Duration
+	// Shutdown закрывается при остановке сервиса: поток завершается,
+	// и клиент переподключается к другой реплике с Last-Event-ID
+	Shutdown <-chan struct{}
+}
+
+// StreamEvents godoc
+// @Summary Application events stream (SSE)
+// @Description Server-Sent Events with changes of the current user's applications (staff receive all changes).
+// @Description Each event has id (use Last-Event-ID to resume), event = routing key and JSON data.
+// @Description Event "reset" means missed events cannot be replayed and the client must reload the list.
+// @Description Browsers' EventSource cannot set headers, so the token may be passed as access_token query parameter.
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Tags UserApplication
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "ID of the last received event"
+// @Param last_event_id query int false "Same as Last-Event-ID header"
+// @Param access_token query string false "JWT for clients that cannot set Authorization header"
+// @Success 200 {string} string "event stream"
+// @Failure 400 {object} map[string]string
+// @Router /api/applications/stream [get]
+func (h *StreamHandler) StreamEvents(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var lastID int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+			return
+		}
+		lastID = id
+	}
+
+	userID := c.GetUint("user_id")
+	all := middleware.IsStaff(c.GetString("role"))
+
+	// подписываемся до чтения журнала, чтобы не потерять события между ними
+	sub := h.Events.Subscribe(userID, all)
+	defer h.Events.Unsubscribe(sub)
+
+	backlog, reset, err := h.Events.Backlog(c.Request.Context(), lastID, userID, all)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+	if reset {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	replayed := make(map[int64]bool, len(backlog))
+	for i := range backlog {
+		replayed[backlog[i].ID] = true
+		if err := writeSSEEvent(w, &backlog[i]); err != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	heartbeat := h.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = DefaultSSEHeartbeat
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.Shutdown:
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		case e, ok := <-sub.C:
+			if !ok {
+				// отключены из-за переполнения: клиент переподключится с Last-Event-ID
+				return
+			}
+			if replayed[e.ID] {
+				continue
+			}
+			if err := writeSSEEvent(w, &e); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	}
+}
+
+func writeSSEEvent(w io.Writer, e *models.ApplicationEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
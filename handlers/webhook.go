@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"shopflow/application/models"
+	"shopflow/application/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultDeliveriesLimit — сколько записей журнала доставок отдавать по умолчанию
+const defaultDeliveriesLimit = 50
+
+type WebhookHandler struct {
+	Webhooks *services.WebhookService
+}
+
+// webhookID разбирает ID подписки из пути; при ошибке ответ уже отправлен
+func webhookID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func webhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+	case errors.Is(err, services.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// CreateWebhook godoc
+// @Summary Create webhook subscription
+// @Description Subscribes a URL to application events. Deliveries are signed: header X-ShopFlow-Signature is
+// @Description "t=<unix timestamp>,v1=<hex HMAC-SHA256(secret, timestamp + '.' + body)>". The secret is returned only here.
+// @Security BearerAuth
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param input body models.CreateWebhookRequest true "Subscription"
+// @Success 201 {object} models.WebhookSubscription
+// @Failure 400 {object} map[string]string
+// @Router /api/admin/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.Webhooks.CreateSubscription(c.Request.Context(), c.GetUint("user_id"), req)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, sub)
+}
+
+// ListWebhooks godoc
+// @Summary List webhook subscriptions
+// @Security BearerAuth
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {array} models.WebhookSubscription
+// @Router /api/admin/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	subs, err := h.Webhooks.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subs)
+}
+
+// GetWebhook godoc
+// @Summary Get webhook subscription
+// @Security BearerAuth
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 404 {object} map[string]string
+// @Router /api/admin/webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	sub, err := h.Webhooks.GetSubscription(c.Request.Context(), id)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// UpdateWebhook godoc
+// @Summary Update webhook subscription
+// @Description Partial update. Setting active=true re-enables a subscription disabled after repeated failures. Setting active=false fails its pending deliveries.
+// @Security BearerAuth
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param input body models.UpdateWebhookRequest true "Fields to change"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/admin/webhooks/{id} [patch]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.Webhooks.UpdateSubscription(c.Request.Context(), id, req)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook subscription
+// @Security BearerAuth
+// @Tags Webhooks
+// @Param id path int true "Subscription ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} map[string]string
+// @Router /api/admin/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	if err := h.Webhooks.DeleteSubscription(c.Request.Context(), id); err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary Webhook delivery log
+// @Description Latest deliveries of a subscription with attempts, last status code and error
+// @Security BearerAuth
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param limit query int false "Max entries (default 50, max 500)"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 404 {object} map[string]string
+// @Router /api/admin/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListWebhookDeliveries(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	limit := defaultDeliveriesLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.Webhooks.ListDeliveries(c.Request.Context(), id, limit)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// SendTestWebhook godoc
+// @Summary Send test event
+// @Description Immediately delivers a "webhook.test" event to the subscription and returns the delivery result
+// @Security BearerAuth
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 404 {object} map[string]string
+// @Router /api/admin/webhooks/{id}/test [post]
+func (h *WebhookHandler) SendTestWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	delivery, err := h.Webhooks.SendTestEvent(c.Request.Context(), id)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
@@ -0,0 +1,24 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Postgres — проверка соединения с БД
+func Postgres(db *sql.DB) CheckFunc {
+	return db.PingContext
+}
+
+// RabbitMQ — проверка, что соединение с брокером открыто
+func RabbitMQ(conn *amqp.Connection) CheckFunc {
+	return func(ctx context.Context) error {
+		if conn == nil || conn.IsClosed() {
+			return errors.New("connection is closed")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ServiceName — имя сервиса в gRPC health-протоколе (кроме общего статуса "")
+const ServiceName = "shopflow.application.ApplicationService"
+
+// SyncGRPC периодически переносит результат проверок в стандартный gRPC health-сервер:
+// общий статус "" и ServiceName, а также статус каждой проверки под её именем.
+// Завершается с ctx; для остановки сервиса вызовите hs.Shutdown().
+func SyncGRPC(ctx context.Context, r *Registry, hs *grpchealth.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report := r.Run(ctx)
+		overall := servingStatus(report.OK())
+		hs.SetServingStatus("", overall)
+		hs.SetServingStatus(ServiceName, overall)
+		for name, res := range report.Checks {
+			hs.SetServingStatus(name, servingStatus(res.Status == StatusOK))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func servingStatus(ok bool) healthpb.HealthCheckResponse_ServingStatus {
+	if ok {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
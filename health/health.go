@@ -0,0 +1,120 @@
+// Package health — проверки готовности зависимостей сервиса (Postgres, RabbitMQ, Auth gRPC).
+// Результаты кэшируются, чтобы частые опросы оркестратора не нагружали зависимости,
+// а каждая проверка ограничена таймаутом.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Статусы проверок
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc — проверка одной зависимости; nil — зависимость доступна
+type CheckFunc func(ctx context.Context) error
+
+// Result — результат одной проверки
+type Result struct {
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// Report — сводный результат проверок
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// OK — все проверки прошли
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+
+	mu     sync.Mutex
+	result Result
+	valid  time.Time
+}
+
+// Registry — набор проверок с кэшированием результатов
+type Registry struct {
+	ttl     time.Duration
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks []*check
+}
+
+// NewRegistry — ttl задаёт время жизни кэша, timeout — предел одной проверки
+func NewRegistry(ttl, timeout time.Duration) *Registry {
+	return &Registry{ttl: ttl, timeout: timeout}
+}
+
+// Add регистрирует проверку
+func (r *Registry) Add(name string, fn CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, &check{name: name, fn: fn})
+}
+
+// Run выполняет все проверки параллельно (или берёт свежий результат из кэша)
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]*check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c *check) {
+			defer wg.Done()
+			res := r.run(ctx, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = res
+			if res.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+// run выполняет проверку; одновременные запросы ждут один общий вызов
+func (r *Registry) run(ctx context.Context, c *check) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Now().Before(c.valid) {
+		return c.result
+	}
+
+	// результат кэшируется для всех, поэтому отмена запроса одного клиента не должна его испортить
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	res := Result{Status: StatusOK, DurationMS: time.Since(start).Milliseconds(), CheckedAt: start}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+
+	c.result = res
+	c.valid = time.Now().Add(r.ttl)
+	return res
+}
@@ -0,0 +1,207 @@
+// Package importer — построчный разбор и валидация заявок для массовой загрузки из CSV и NDJSON.
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"shopflow/application/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые форматы загрузки
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Ограничения колонок user_applications
+const (
+	maxStatusLength  = 20
+	maxFileURLLength = 120
+	maxNDJSONLine    = 1 << 20
+)
+
+// knownColumns — колонки, которые понимает загрузка. id, version и assignee_id допускаются,
+// чтобы можно было загрузить файл выгрузки, но игнорируются: id и version назначает БД,
+// а назначать заявки могут только сотрудники.
+var knownColumns = map[string]bool{
+	"id": true, "user_id": true, "text": true, "file_url": true,
+	"status": true, "version": true, "created_at": true, "updated_at": true,
+	"assignee_id": true,
+}
+
+// timeLayouts — допустимые форматы дат в загружаемых файлах
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// Row — одна разобранная строка файла. Если Err != nil, строка невалидна и App не заполнен.
+// Нулевые CreatedAt/UpdatedAt и пустой Status означают, что значение в файле не задано.
+type Row struct {
+	Line int
+	App  models.Application
+	Err  error
+}
+
+// Reader читает строки файла по одной; в конце возвращает io.EOF
+type Reader interface {
+	Next() (Row, error)
+}
+
+// NewReader создаёт читателя для формата
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.ReuseRecord = true
+		return &csvReader{r: cr}, nil
+	case FormatNDJSON:
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64*1024), maxNDJSONLine)
+		return &ndjsonReader{sc: sc}, nil
+	}
+	return nil, fmt.Errorf("unsupported import format %q", format)
+}
+
+type csvReader struct {
+	r       *csv.Reader
+	columns []string
+}
+
+func (c *csvReader) Next() (Row, error) {
+	if c.columns == nil {
+		header, err := c.r.Read()
+		if errors.Is(err, io.EOF) {
+			return Row{}, fmt.Errorf("csv header is missing")
+		}
+		if err != nil {
+			return Row{}, err
+		}
+		for _, col := range header {
+			col = strings.ToLower(strings.TrimSpace(col))
+			if !knownColumns[col] {
+				return Row{}, fmt.Errorf("unknown column %q in csv header", col)
+			}
+			c.columns = append(c.columns, col)
+		}
+	}
+
+	record, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Row{Line: parseErr.Line, Err: parseErr.Err}, nil
+		}
+		return Row{}, err
+	}
+	line, _ := c.r.FieldPos(0)
+	if len(record) != len(c.columns) {
+		return Row{Line: line, Err: fmt.Errorf("expected %d fields, got %d", len(c.columns), len(record))}, nil
+	}
+
+	fields := make(map[string]string, len(record))
+	for i, v := range record {
+		fields[c.columns[i]] = v
+	}
+	return parseFields(line, fields), nil
+}
+
+type ndjsonReader struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+// ndjsonRow — строка NDJSON; значения принимаются и строками, и числами
+type ndjsonRow struct {
+	UserID    json.RawMessage `json:"user_id"`
+	Text      string          `json:"text"`
+	FileURL   string          `json:"file_url"`
+	Status    string          `json:"status"`
+	CreatedAt string          `json:"created_at"`
+	UpdatedAt string          `json:"updated_at"`
+}
+
+func (n *ndjsonReader) Next() (Row, error) {
+	for n.sc.Scan() {
+		n.line++
+		text := strings.TrimSpace(n.sc.Text())
+		if text == "" {
+			continue
+		}
+
+		var raw ndjsonRow
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return Row{Line: n.line, Err: fmt.Errorf("invalid json: %w", err)}, nil
+		}
+		return parseFields(n.line, map[string]string{
+			"user_id":    strings.Trim(string(raw.UserID), `"`),
+			"text":       raw.Text,
+			"file_url":   raw.FileURL,
+			"status":     raw.Status,
+			"created_at": raw.CreatedAt,
+			"updated_at": raw.UpdatedAt,
+		}), nil
+	}
+	if err := n.sc.Err(); err != nil {
+		return Row{}, err
+	}
+	return Row{}, io.EOF
+}
+
+// parseFields проверяет значения строки и собирает заявку
+func parseFields(line int, fields map[string]string) Row {
+	row := Row{Line: line}
+	fail := func(format string, args ...any) Row {
+		return Row{Line: line, Err: fmt.Errorf(format, args...)}
+	}
+
+	userID, err := strconv.ParseUint(strings.TrimSpace(fields["user_id"]), 10, 32)
+	if err != nil || userID == 0 {
+		return fail("invalid user_id %q", fields["user_id"])
+	}
+	row.App.UserID = uint(userID)
+
+	row.App.Text = fields["text"]
+	if strings.TrimSpace(row.App.Text) == "" {
+		return fail("text is required")
+	}
+
+	row.App.FileURL = strings.TrimSpace(fields["file_url"])
+	if len(row.App.FileURL) > maxFileURLLength {
+		return fail("file_url is longer than %d characters", maxFileURLLength)
+	}
+
+	row.App.Status = strings.TrimSpace(fields["status"])
+	if len(row.App.Status) > maxStatusLength {
+		return fail("status is longer than %d characters", maxStatusLength)
+	}
+
+	if row.App.CreatedAt, err = parseTime(fields["created_at"]); err != nil {
+		return fail("invalid created_at %q", fields["created_at"])
+	}
+	if row.App.UpdatedAt, err = parseTime(fields["updated_at"]); err != nil {
+		return fail("invalid updated_at %q", fields["updated_at"])
+	}
+	if !row.App.CreatedAt.IsZero() && !row.App.UpdatedAt.IsZero() && row.App.UpdatedAt.Before(row.App.CreatedAt) {
+		return fail("updated_at is before created_at")
+	}
+	return row
+}
+
+func parseTime(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format")
+}
@@ -0,0 +1,332 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"shopflow/application/export"
+	"shopflow/application/models"
+)
+
+// readAll читает все строки файла
+func readAll(t *testing.T, format string, data []byte) []Row {
+	t.Helper()
+	r, err := NewReader(format, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []Row
+	for {
+		row, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestImportExportRoundTrip(t *testing.T) {
+	created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	assignee := uint(7)
+	apps := []models.Application{
+		{ID: 1, UserID: 42, Text: "first", FileURL: "https://files/1", Status: "new", Version: 1,
+			CreatedAt: created, UpdatedAt: created},
+		{ID: 2, UserID: 43, Text: "second, with comma", Status: "in_review", Version: 3,
+			CreatedAt: created, UpdatedAt: created.Add(time.Hour), AssigneeID: &assignee},
+	}
+
+	for _, format := range []string{export.FormatCSV, export.FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			formatter, err := export.NewFormatter(models.ExportOptions{Format: format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			w, err := export.NewRowWriter(format, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteHeader(formatter.Columns()); err != nil {
+				t.Fatal(err)
+			}
+			for i := range apps {
+				if err := w.WriteRow(formatter.Values(&apps[i])); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			rows := readAll(t, format, buf.Bytes())
+			if len(rows) != len(apps) {
+				t.Fatalf("got %d rows, want %d", len(rows), len(apps))
+			}
+			for i, row := range rows {
+				if row.Err != nil {
+					t.Fatalf("row %d: %v", i, row.Err)
+				}
+				want := apps[i]
+				got := row.App
+				if got.UserID != want.UserID || got.Text != want.Text || got.FileURL != want.FileURL || got.Status != want.Status {
+					t.Errorf("row %d = %+v, want %+v", i, got, want)
+				}
+				if !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+					t.Errorf("row %d dates = %v/%v, want %v/%v", i, got.CreatedAt, got.UpdatedAt, want.CreatedAt, want.UpdatedAt)
+				}
+				// id, version и назначение задаются при загрузке заново
+				if got.ID != 0 || got.Version != 0 || got.AssigneeID != nil {
+					t.Errorf("row %d: id, version and assignee must be ignored, got %+v", i, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	valid := func() map[string]string {
+		return map[string]string{
+			"user_id":    "42",
+			"text":       "hello",
+			"file_url":   "https://files/1",
+			"status":     "new",
+			"created_at": "2026-03-01T10:00:00Z",
+			"updated_at": "2026-03-01T11:00:00Z",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(map[string]string)
+		wantErr string
+		check   func(t *testing.T, app models.Application)
+	}{
+		{name: "valid", change: func(m map[string]string) {}},
+		{name: "user_id with spaces", change: func(m map[string]string) { m["user_id"] = " 42 " }},
+		{name: "user_id missing", change: func(m map[string]string) { delete(m, "user_id") }, wantErr: `invalid user_id ""`},
+		{name: "user_id zero", change: func(m map[string]string) { m["user_id"] = "0" }, wantErr: `invalid user_id "0"`},
+		{name: "user_id text", change: func(m map[string]string) { m["user_id"] = "abc" }, wantErr: `invalid user_id "abc"`},
+		{name: "user_id negative", change: func(m map[string]string) { m["user_id"] = "-1" }, wantErr: `invalid user_id "-1"`},
+		{name: "text blank", change: func(m map[string]string) { m["text"] = "  " }, wantErr: "text is required"},
+		{name: "file_url too long", change: func(m map[string]string) { m["file_url"] = strings.Repeat("x", 121) }, wantErr: "file_url is longer than 120 characters"},
+		{name: "file_url optional", change: func(m map[string]string) { delete(m, "file_url") }},
+		{name: "status too long", change: func(m map[string]string) { m["status"] = strings.Repeat("s", 21) }, wantErr: "status is longer than 20 characters"},
+		{
+			name:   "status and dates optional",
+			change: func(m map[string]string) { delete(m, "status"); delete(m, "created_at"); delete(m, "updated_at") },
+			check: func(t *testing.T, app models.Application) {
+				if app.Status != "" || !app.CreatedAt.IsZero() || !app.UpdatedAt.IsZero() {
+					t.Errorf("unset values must stay zero, got %+v", app)
+				}
+			},
+		},
+		{
+			name:   "space separated time",
+			change: func(m map[string]string) { m["created_at"] = "2026-03-01 10:00:00" },
+			check: func(t *testing.T, app models.Application) {
+				if want := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC); !app.CreatedAt.Equal(want) {
+					t.Errorf("created_at = %v, want %v", app.CreatedAt, want)
+				}
+			},
+		},
+		{name: "time without zone", change: func(m map[string]string) { m["created_at"] = "2026-03-01T10:00:00" }},
+		{name: "date only", change: func(m map[string]string) { m["created_at"] = "2026-03-01" }},
+		{name: "time with offset", change: func(m map[string]string) { m["updated_at"] = "2026-03-01T14:00:00+03:00" }},
+		{name: "unsupported time", change: func(m map[string]string) { m["created_at"] = "01.03.2026" }, wantErr: `invalid created_at "01.03.2026"`},
+		{name: "invalid updated_at", change: func(m map[string]string) { m["updated_at"] = "yesterday" }, wantErr: `invalid updated_at "yesterday"`},
+		{name: "updated before created", change: func(m map[string]string) { m["updated_at"] = "2026-03-01T09:59:59Z" }, wantErr: "updated_at is before created_at"},
+		{name: "updated equals created", change: func(m map[string]string) { m["updated_at"] = m["created_at"] }},
+		{name: "only updated_at", change: func(m map[string]string) { delete(m, "created_at") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := valid()
+			tt.change(fields)
+			row := parseFields(7, fields)
+
+			if row.Line != 7 {
+				t.Errorf("line = %d, want 7", row.Line)
+			}
+			if tt.wantErr != "" {
+				if row.Err == nil || row.Err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", row.Err, tt.wantErr)
+				}
+				return
+			}
+			if row.Err != nil {
+				t.Fatalf("unexpected error: %v", row.Err)
+			}
+			if row.App.UserID != 42 {
+				t.Errorf("user_id = %d, want 42", row.App.UserID)
+			}
+			if tt.check != nil {
+				tt.check(t, row.App)
+			}
+		})
+	}
+}
+
+// rowResult — ожидаемый результат строки: номер строки файла и текст ошибки ("" — строка валидна)
+type rowResult struct {
+	line int
+	err  string
+}
+
+func checkRows(t *testing.T, rows []Row, want []rowResult) {
+	t.Helper()
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i, row := range rows {
+		got := ""
+		if row.Err != nil {
+			got = row.Err.Error()
+		}
+		if row.Line != want[i].line || !strings.Contains(got, want[i].err) || (want[i].err == "") != (got == "") {
+			t.Errorf("row %d = line %d, error %q; want line %d, error %q", i, row.Line, got, want[i].line, want[i].err)
+		}
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []rowResult
+	}{
+		{
+			name: "valid rows",
+			data: "user_id,text,file_url\n1,a,https://f/1\n2,b,https://f/2\n",
+			want: []rowResult{{line: 2}, {line: 3}},
+		},
+		{
+			name: "header is case and space insensitive",
+			data: " User_ID , TEXT \n1,a\n",
+			want: []rowResult{{line: 2}},
+		},
+		{
+			name: "errors are reported per line",
+			data: "user_id,text\n1,a\nabc,b\n3,\n4,d\n",
+			want: []rowResult{{line: 2}, {line: 3, err: `invalid user_id "abc"`}, {line: 4, err: "text is required"}, {line: 5}},
+		},
+		{
+			name: "field count mismatch",
+			data: "user_id,text,status\n1,a,new\n2,b\n3,c,new,extra\n4,d,new\n",
+			want: []rowResult{{line: 2}, {line: 3, err: "expected 3 fields, got 2"}, {line: 4, err: "expected 3 fields, got 4"}, {line: 5}},
+		},
+		{
+			name: "quoted multiline text keeps line of the record start",
+			data: "user_id,text\n1,\"first\nsecond\"\n2,x\n",
+			want: []rowResult{{line: 2}, {line: 4}},
+		},
+		{
+			name: "malformed quoting",
+			data: "user_id,text\n1,\"bad\"quote\n2,ok\n",
+			want: []rowResult{{line: 2, err: "extraneous"}, {line: 3}},
+		},
+		{
+			name: "dates are validated",
+			data: "user_id,text,created_at,updated_at\n1,a,2026-03-02,2026-03-01\n2,b,someday,\n",
+			want: []rowResult{{line: 2, err: "updated_at is before created_at"}, {line: 3, err: `invalid created_at "someday"`}},
+		},
+		{
+			name: "header only",
+			data: "user_id,text\n",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkRows(t, readAll(t, FormatCSV, []byte(tt.data)), tt.want)
+		})
+	}
+}
+
+func TestCSVReaderHeaderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "empty file", data: "", wantErr: "csv header is missing"},
+		{name: "unknown column", data: "user_id,text,color\n1,a,red\n", wantErr: `unknown column "color" in csv header`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(FormatCSV, strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Next(); err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []rowResult
+	}{
+		{
+			name: "user_id as number and string",
+			data: `{"user_id":1,"text":"a"}` + "\n" + `{"user_id":"2","text":"b"}` + "\n",
+			want: []rowResult{{line: 1}, {line: 2}},
+		},
+		{
+			name: "blank lines are skipped but counted",
+			data: `{"user_id":1,"text":"a"}` + "\n\n   \n" + `{"user_id":2,"text":"b"}`,
+			want: []rowResult{{line: 1}, {line: 4}},
+		},
+		{
+			name: "errors are reported per line",
+			data: `{"user_id":1,"text":"a"}` + "\n" + `{"user_id":1,"text":` + "\n" + `{"user_id":0,"text":"c"}` + "\n" + `{"user_id":4,"text":"d","updated_at":"2020-01-01","created_at":"2021-01-01"}` + "\n" + `{"user_id":5,"text":"e"}`,
+			want: []rowResult{
+				{line: 1},
+				{line: 2, err: "invalid json"},
+				{line: 3, err: `invalid user_id "0"`},
+				{line: 4, err: "updated_at is before created_at"},
+				{line: 5},
+			},
+		},
+		{
+			name: "user_id null",
+			data: `{"user_id":null,"text":"a"}`,
+			want: []rowResult{{line: 1, err: `invalid user_id "null"`}},
+		},
+		{
+			name: "unknown fields are ignored",
+			data: `{"id":9,"user_id":1,"text":"a","version":3,"assignee_id":null}`,
+			want: []rowResult{{line: 1}},
+		},
+		{
+			name: "empty input",
+			data: "",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkRows(t, readAll(t, FormatNDJSON, []byte(tt.data)), tt.want)
+		})
+	}
+}
+
+func TestNewReaderUnknownFormat(t *testing.T) {
+	if _, err := NewReader("xml", strings.NewReader("")); err == nil {
+		t.Fatal("NewReader accepted an unknown format")
+	}
+}
@@ -0,0 +1,225 @@
+// Package jwks — открытые ключи проверки асимметричных JWT (RS*, PS*, ES*, EdDSA):
+// из JWKS-эндпоинта Auth сервиса и из статических PEM-файлов.
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"shopflow/application/logging"
+)
+
+// ErrUnknownKey — ключа с таким kid нет ни среди статических, ни в JWKS
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Options — настройки загрузки JWKS
+type Options struct {
+	// RefreshInterval — как часто перечитывать JWKS в фоне (Run)
+	RefreshInterval time.Duration
+	// MinRefreshInterval — не чаще этого JWKS перечитывается из-за неизвестного kid
+	// (защита Auth от потока токенов с выдуманными kid)
+	MinRefreshInterval time.Duration
+	// Timeout — предел одного запроса JWKS
+	Timeout time.Duration
+}
+
+// DefaultOptions — настройки по умолчанию
+func DefaultOptions() Options {
+	return Options{
+		RefreshInterval:    10 * time.Minute,
+		MinRefreshInterval: 30 * time.Second,
+		Timeout:            5 * time.Second,
+	}
+}
+
+// Keyring — набор ключей проверки: статические PEM-ключи и ключи из JWKS.
+// JWKS кэшируется и перечитывается периодически и при встрече неизвестного kid,
+// поэтому Auth может менять ключи без передеплоя сервиса.
+type Keyring struct {
+	url    string
+	client *http.Client
+	opts   Options
+	static map[string]crypto.PublicKey
+
+	refresh sync.Mutex // один запрос JWKS за раз
+
+	mu          sync.RWMutex
+	remote      map[string]remoteKey
+	lastAttempt time.Time
+}
+
+type remoteKey struct {
+	key crypto.PublicKey
+	alg string
+}
+
+// New создаёт набор ключей. url — адрес JWKS (пусто — только статические ключи),
+// static — ключи из PEM-файлов по kid (LoadPEMFiles). JWKS не запрашивается до первого
+// обращения или Refresh.
+func New(url string, static map[string]crypto.PublicKey, opts Options) *Keyring {
+	return &Keyring{
+		url:    url,
+		client: &http.Client{Timeout: opts.Timeout},
+		opts:   opts,
+		static: static,
+		remote: make(map[string]remoteKey),
+	}
+}
+
+// Lookup возвращает ключи для проверки токена с заголовками kid и alg.
+// Без kid возвращаются все известные ключи — библиотека JWT попробует каждый.
+func (k *Keyring) Lookup(ctx context.Context, kid, alg string) ([]crypto.PublicKey, error) {
+	if kid == "" {
+		keys := k.all()
+		if len(keys) == 0 {
+			return nil, ErrUnknownKey
+		}
+		return keys, nil
+	}
+
+	if key, ok := k.static[kid]; ok {
+		return []crypto.PublicKey{key}, nil
+	}
+	key, ok := k.remoteKey(kid)
+	if !ok && k.url != "" {
+		// возможно, Auth уже перешёл на новый ключ
+		if err := k.refreshUnknown(ctx); err != nil {
+			slog.WarnContext(ctx, "failed to refresh JWKS", "component", "jwks", logging.Err(err))
+		}
+		key, ok = k.remoteKey(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+	if key.alg != "" && key.alg != alg {
+		return nil, fmt.Errorf("key %q is for %s, token is signed with %s", kid, key.alg, alg)
+	}
+	return []crypto.PublicKey{key.key}, nil
+}
+
+func (k *Keyring) all() []crypto.PublicKey {
+	keys := make([]crypto.PublicKey, 0, len(k.static))
+	for _, key := range k.static {
+		keys = append(keys, key)
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.remote {
+		keys = append(keys, key.key)
+	}
+	return keys
+}
+
+func (k *Keyring) remoteKey(kid string) (remoteKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.remote[kid]
+	return key, ok
+}
+
+// refreshUnknown перечитывает JWKS из-за неизвестного kid, но не чаще MinRefreshInterval;
+// одновременные запросы с новым kid дожидаются одного обращения к Auth
+func (k *Keyring) refreshUnknown(ctx context.Context) error {
+	k.refresh.Lock()
+	defer k.refresh.Unlock()
+
+	k.mu.RLock()
+	allowed := time.Since(k.lastAttempt) >= k.opts.MinRefreshInterval
+	k.mu.RUnlock()
+	if !allowed {
+		return nil
+	}
+	return k.load(ctx)
+}
+
+// Refresh перечитывает JWKS. При ошибке прежние ключи остаются в силе.
+func (k *Keyring) Refresh(ctx context.Context) error {
+	if k.url == "" {
+		return nil
+	}
+	k.refresh.Lock()
+	defer k.refresh.Unlock()
+	return k.load(ctx)
+}
+
+// load запрашивает JWKS; вызывается под k.refresh
+func (k *Keyring) load(ctx context.Context) error {
+	k.mu.Lock()
+	k.lastAttempt = time.Now()
+	k.mu.Unlock()
+
+	keys, err := k.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	k.remote = keys
+	k.mu.Unlock()
+	slog.DebugContext(ctx, "JWKS refreshed", "component", "jwks", "keys", len(keys))
+	return nil
+}
+
+func (k *Keyring) fetch(ctx context.Context) (map[string]remoteKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]remoteKey, len(set.Keys))
+	for _, j := range set.Keys {
+		if j.Kid == "" || (j.Use != "" && j.Use != "sig") {
+			continue
+		}
+		key, err := j.publicKey()
+		if err != nil {
+			// один непонятный ключ не должен ломать остальные
+			slog.WarnContext(ctx, "skipping JWKS key", "component", "jwks", "kid", j.Kid, logging.Err(err))
+			continue
+		}
+		keys[j.Kid] = remoteKey{key: key, alg: j.Alg}
+	}
+	return keys, nil
+}
+
+// Run периодически перечитывает JWKS. Завершается с ctx.
+func (k *Keyring) Run(ctx context.Context) {
+	if k.url == "" || k.opts.RefreshInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(k.opts.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := k.Refresh(ctx); err != nil {
+			slog.WarnContext(ctx, "failed to refresh JWKS", "component", "jwks", logging.Err(err))
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package jwks
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksStub — JWKS-эндпоинт Auth: отдаёт ключи с заданными kid и считает запросы
+type jwksStub struct {
+	*httptest.Server
+	requests atomic.Int32
+
+	mu   sync.Mutex
+	kids []string
+}
+
+func newJWKSStub(t *testing.T, kids ...string) *jwksStub {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := base64.RawURLEncoding.EncodeToString(pub)
+
+	s := &jwksStub{kids: kids}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		s.mu.Lock()
+		keys := make([]jwk, 0, len(s.kids))
+		for _, kid := range s.kids {
+			keys = append(keys, jwk{Kty: "OKP", Crv: "Ed25519", X: x, Kid: kid, Use: "sig", Alg: "EdDSA"})
+		}
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksStub) setKids(kids ...string) {
+	s.mu.Lock()
+	s.kids = kids
+	s.mu.Unlock()
+}
+
+func TestLookupRefreshesOnUnknownKid(t *testing.T) {
+	tests := []struct {
+		name         string
+		minRefresh   time.Duration
+		lookups      []string
+		wantRequests int32
+		wantUnknown  string // kid, для которого последний Lookup возвращает ErrUnknownKey
+	}{
+		{
+			name:         "known kid is cached",
+			minRefresh:   time.Hour,
+			lookups:      []string{"k1", "k1", "k1"},
+			wantRequests: 1,
+		},
+		{
+			name:         "unknown kid refreshes at most once per interval",
+			minRefresh:   time.Hour,
+			lookups:      []string{"k1", "bogus", "bogus", "other", "bogus"},
+			wantRequests: 1,
+			wantUnknown:  "bogus",
+		},
+		{
+			name:         "unknown kid as the first lookup",
+			minRefresh:   time.Hour,
+			lookups:      []string{"bogus", "bogus", "k1"},
+			wantRequests: 1,
+		},
+		{
+			name:         "without interval every unknown kid refreshes",
+			minRefresh:   0,
+			lookups:      []string{"k1", "bogus", "bogus"},
+			wantRequests: 3,
+			wantUnknown:  "bogus",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newJWKSStub(t, "k1")
+			opts := DefaultOptions()
+			opts.MinRefreshInterval = tt.minRefresh
+			k := New(stub.URL, nil, opts)
+
+			var lastErr error
+			for _, kid := range tt.lookups {
+				_, lastErr = k.Lookup(context.Background(), kid, "EdDSA")
+			}
+
+			if got := stub.requests.Load(); got != tt.wantRequests {
+				t.Errorf("JWKS requests = %d, want %d", got, tt.wantRequests)
+			}
+			if tt.wantUnknown != "" {
+				if !errors.Is(lastErr, ErrUnknownKey) {
+					t.Errorf("Lookup(%q) error = %v, want ErrUnknownKey", tt.wantUnknown, lastErr)
+				}
+			} else if lastErr != nil {
+				t.Errorf("Lookup error = %v", lastErr)
+			}
+		})
+	}
+}
+
+func TestLookupConcurrentUnknownKidsShareOneRefresh(t *testing.T) {
+	stub := newJWKSStub(t, "k1")
+	opts := DefaultOptions()
+	opts.MinRefreshInterval = time.Hour
+	k := New(stub.URL, nil, opts)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = k.Lookup(context.Background(), "bogus", "EdDSA")
+		}()
+	}
+	wg.Wait()
+
+	if got := stub.requests.Load(); got != 1 {
+		t.Errorf("JWKS requests = %d, want 1", got)
+	}
+}
+
+func TestLookupPicksUpRotatedKeyAfterInterval(t *testing.T) {
+	stub := newJWKSStub(t, "k1")
+	opts := DefaultOptions()
+	opts.MinRefreshInterval = 50 * time.Millisecond
+	k := New(stub.URL, nil, opts)
+
+	if _, err := k.Lookup(context.Background(), "k1", "EdDSA"); err != nil {
+		t.Fatalf("Lookup(k1): %v", err)
+	}
+	stub.setKids("k1", "k2")
+
+	// до истечения интервала новый kid ещё неизвестен
+	if _, err := k.Lookup(context.Background(), "k2", "EdDSA"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Lookup(k2) before interval: error = %v, want ErrUnknownKey", err)
+	}
+	time.Sleep(opts.MinRefreshInterval)
+	if _, err := k.Lookup(context.Background(), "k2", "EdDSA"); err != nil {
+		t.Fatalf("Lookup(k2) after interval: %v", err)
+	}
+	if got := stub.requests.Load(); got != 2 {
+		t.Errorf("JWKS requests = %d, want 2", got)
+	}
+}
+
+func TestLookupRejectsAlgorithmMismatch(t *testing.T) {
+	stub := newJWKSStub(t, "k1")
+	k := New(stub.URL, nil, DefaultOptions())
+
+	if _, err := k.Lookup(context.Background(), "k1", "RS256"); err == nil {
+		t.Fatal("Lookup with a foreign algorithm succeeded")
+	}
+}
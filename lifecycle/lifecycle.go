@@ -0,0 +1,136 @@
+// Package lifecycle — упорядоченная остановка сервиса по SIGINT/SIGTERM.
+// Ресурсы регистрируются в порядке запуска и останавливаются в обратном:
+// сначала перестаём принимать трафик, затем останавливаем воркеры, досылаем
+// события и только потом закрываем соединения с брокером и БД.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type hook struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Manager хранит хуки остановки и фоновые воркеры
+type Manager struct {
+	mu       sync.Mutex
+	hooks    []hook
+	stopping chan struct{}
+	once     sync.Once
+}
+
+func New() *Manager {
+	return &Manager{stopping: make(chan struct{})}
+}
+
+// OnStop регистрирует хук остановки. Хуки выполняются в порядке, обратном регистрации.
+func (m *Manager) OnStop(name string, stop func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{name: name, stop: stop})
+}
+
+// OnClose регистрирует закрытие ресурса без контекста (БД, соединение с брокером)
+func (m *Manager) OnClose(name string, close func() error) {
+	m.OnStop(name, func(context.Context) error { return close() })
+}
+
+// Go запускает фоновый воркер. При остановке его контекст отменяется,
+// и менеджер ждёт завершения воркера (в том месте очереди хуков, где он был запущен).
+func (m *Manager) Go(name string, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+
+	m.OnStop(name, func(stopCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return stopCtx.Err()
+		}
+	})
+}
+
+// Stopping закрывается в начале остановки; долгоживущие соединения (SSE, WebSocket)
+// должны по нему завершаться, иначе HTTP-сервер не сможет дождаться их окончания.
+func (m *Manager) Stopping() <-chan struct{} {
+	return m.stopping
+}
+
+// IsStopping — остановка уже началась (для проверки готовности)
+func (m *Manager) IsStopping() bool {
+	select {
+	case <-m.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait блокируется до SIGINT/SIGTERM или закрытия fatal, после чего выполняет Shutdown.
+// fatal позволяет начать остановку, если, например, HTTP-сервер не смог запуститься.
+func (m *Manager) Wait(fatal <-chan error, delay, timeout time.Duration) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	var cause error
+	select {
+	case sig := <-signals:
+		slog.Info("received signal, shutting down", "component", "lifecycle", "signal", sig.String())
+	case cause = <-fatal:
+		slog.Error("fatal error, shutting down", "component", "lifecycle", "error", cause)
+		delay = 0
+	}
+
+	err := m.Shutdown(delay, timeout)
+	return errors.Join(cause, err)
+}
+
+// Shutdown останавливает сервис: помечает его неготовым, ждёт delay (чтобы балансировщик
+// успел убрать реплику), затем выполняет хуки в обратном порядке, укладываясь в timeout.
+// Хуки, не успевшие до дедлайна, получают отменённый контекст, но всё равно вызываются,
+// чтобы соединения были закрыты.
+func (m *Manager) Shutdown(delay, timeout time.Duration) error {
+	m.once.Do(func() { close(m.stopping) })
+	if delay > 0 {
+		slog.Info("waiting before draining", "component", "lifecycle", "delay", delay)
+		time.Sleep(delay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	m.mu.Lock()
+	hooks := m.hooks
+	m.hooks = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		start := time.Now()
+		if err := h.stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			slog.Error("stopped with error", "component", "lifecycle", "hook", h.name, "duration", time.Since(start), "error", err)
+			continue
+		}
+		slog.Info("stopped", "component", "lifecycle", "hook", h.name, "duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,120 @@
+// Package logging — структурные JSON-логи (slog) с полями корреляции из контекста:
+// request_id, user_id, application_id, trace_id и span_id.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// level — текущий уровень логирования, меняется без перезапуска (SetLevel)
+var level = new(slog.LevelVar)
+
+// Setup делает JSON-логгер в stdout логгером по умолчанию. Через него же
+// идут сообщения стандартного пакета log (например, из библиотек).
+func Setup(lvl string) error {
+	if err := SetLevel(lvl); err != nil {
+		return err
+	}
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(contextHandler{handler}))
+	return nil
+}
+
+// SetLevel меняет уровень логирования: debug, info, warn или error
+func SetLevel(lvl string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(lvl))); err != nil {
+		return fmt.Errorf("invalid log level %q", lvl)
+	}
+	level.Set(l)
+	return nil
+}
+
+// Level — текущий уровень логирования
+func Level() string {
+	return strings.ToLower(level.Level().String())
+}
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	userIDKey
+	applicationIDKey
+)
+
+// WithRequestID сохраняет ID запроса в контексте
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID — ID запроса из контекста ("" если нет)
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithUserID сохраняет ID авторизованного пользователя в контексте
+func WithUserID(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, userIDKey, id)
+}
+
+// WithApplicationID сохраняет ID заявки, с которой работает запрос
+func WithApplicationID(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, applicationIDKey, id)
+}
+
+// contextHandler дописывает в каждую запись поля корреляции из контекста
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if id, ok := ctx.Value(userIDKey).(uint); ok {
+			r.AddAttrs(slog.Uint64("user_id", uint64(id)))
+		}
+		// ID заявки, указанный в самой записи, важнее ID из контекста запроса (batch, фоновые события)
+		if id, ok := ctx.Value(applicationIDKey).(uint); ok && !hasAttr(r, "application_id") {
+			r.AddAttrs(slog.Uint64("application_id", uint64(id)))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(
+				slog.String("trace_id", sc.TraceID().String()),
+				slog.String("span_id", sc.SpanID().String()),
+			)
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func hasAttr(r slog.Record, key string) bool {
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = a.Key == key
+		return !found
+	})
+	return found
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Err — атрибут ошибки
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
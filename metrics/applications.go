@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"shopflow/application/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StatusCounter — подсчёт заявок по статусам (ApplicationRepository.CountByStatus)
+type StatusCounter func(ctx context.Context) (map[string]int, error)
+
+// applicationsCollector отдаёт gauge shopflow_applications{status}. Подсчёт делается
+// при сборе метрик, но не чаще раза в ttl, чтобы частый scrape не нагружал БД.
+type applicationsCollector struct {
+	count   StatusCounter
+	ttl     time.Duration
+	timeout time.Duration
+	desc    *prometheus.Desc
+
+	mu      sync.Mutex
+	cached  map[string]int
+	expires time.Time
+}
+
+// RegisterApplicationsByStatus регистрирует gauge заявок по статусам
+func RegisterApplicationsByStatus(count StatusCounter, ttl time.Duration) error {
+	return prometheus.Register(&applicationsCollector{
+		count:   count,
+		ttl:     ttl,
+		timeout: 5 * time.Second,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "applications"),
+			"Applications by status.",
+			[]string{"status"}, nil,
+		),
+	})
+}
+
+func (c *applicationsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *applicationsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Now().After(c.expires) {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		counts, err := c.count(ctx)
+		cancel()
+		if err != nil {
+			// отдаём прошлое значение, чтобы не было дыр в графиках
+			slog.Error("failed to count applications by status", "component", "metrics", logging.Err(err))
+		} else {
+			c.cached = counts
+		}
+		c.expires = time.Now().Add(c.ttl)
+	}
+
+	for status, n := range c.cached {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), status)
+	}
+}
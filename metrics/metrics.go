@@ -0,0 +1,163 @@
+// Package metrics — метрики Prometheus сервиса (отдаются на /metrics).
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "shopflow"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"method", "route", "code"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	httpInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	dbDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of repository methods.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"repository", "method"})
+
+	published = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_published_total",
+		Help:      "Messages published to RabbitMQ by routing key and result (success, failure).",
+	}, []string{"routing_key", "result"})
+
+	verifyTokenDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "auth_verify_token_duration_seconds",
+		Help:      "Latency of Auth VerifyToken gRPC calls.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	verifyTokenResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_verify_token_total",
+		Help:      "Auth VerifyToken calls by result (valid, invalid, error).",
+	}, []string{"result"})
+
+	verifyTokenCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_verify_cache_total",
+		Help:      "Token verification cache lookups by result (hit, miss).",
+	}, []string{"result"})
+
+	verifyTokenUnavailable = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_verify_unavailable_total",
+		Help:      "Verifications that could not reach Auth, by applied policy (fail_open, fail_closed).",
+	}, []string{"policy"})
+
+	authBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "auth_circuit_breaker_state",
+		Help:      "State of the Auth circuit breaker: 0 closed, 1 half-open, 2 open.",
+	})
+)
+
+// Результаты VerifyToken
+const (
+	VerifyValid   = "valid"
+	VerifyInvalid = "invalid"
+	VerifyError   = "error"
+)
+
+// Политики при недоступности Auth
+const (
+	PolicyFailOpen   = "fail_open"
+	PolicyFailClosed = "fail_closed"
+)
+
+// Состояния circuit breaker Auth (значения гейджа)
+const (
+	BreakerClosed   = 0
+	BreakerHalfOpen = 1
+	BreakerOpen     = 2
+)
+
+// unmatchedRoute — метка для запросов, не попавших ни в один маршрут (не раздувает кардинальность)
+const unmatchedRoute = "unmatched"
+
+// GinMiddleware собирает RED-метрики HTTP по шаблону маршрута (/api/applications/:id, а не по URL)
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveQuery начинает замер метода репозитория; вызывается как
+// defer metrics.ObserveQuery("application", "GetAll")()
+func ObserveQuery(repository, method string) func() {
+	start := time.Now()
+	return func() {
+		dbDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Published учитывает публикацию сообщения в брокер
+func Published(routingKey string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	published.WithLabelValues(routingKey, result).Inc()
+}
+
+// VerifyToken учитывает вызов VerifyToken: длительность и результат
+func VerifyToken(start time.Time, result string) {
+	verifyTokenDuration.Observe(time.Since(start).Seconds())
+	verifyTokenResults.WithLabelValues(result).Inc()
+}
+
+// VerifyTokenCache учитывает обращение к кэшу проверок токенов
+func VerifyTokenCache(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	verifyTokenCache.WithLabelValues(result).Inc()
+}
+
+// VerifyTokenUnavailable учитывает проверку, не дошедшую до Auth, и применённую политику
+func VerifyTokenUnavailable(policy string) {
+	verifyTokenUnavailable.WithLabelValues(policy).Inc()
+}
+
+// AuthBreakerState — текущее состояние circuit breaker Auth (BreakerClosed, BreakerHalfOpen, BreakerOpen)
+func AuthBreakerState(state int) {
+	authBreakerState.Set(float64(state))
+}
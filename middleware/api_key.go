@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"shopflow/application/logging"
+	"shopflow/application/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader — заголовок с API-ключом сервиса (альтернатива Bearer-токену)
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator — проверка API-ключей (services.APIKeyService).
+// nil без ошибки — ключ неизвестен, отозван или истёк.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+type apiKeyHolder struct{ APIKeyAuthenticator }
+
+var apiKeys atomic.Pointer[apiKeyHolder]
+
+// SetAPIKeyAuthenticator включает вход по API-ключу в AuthMiddleware (nil — выключен)
+func SetAPIKeyAuthenticator(a APIKeyAuthenticator) {
+	apiKeys.Store(&apiKeyHolder{a})
+}
+
+// authenticateAPIKey — ветка AuthMiddleware для запросов с X-API-Key. Запрос выполняется
+// от имени учётной записи ключа; роль выводится из областей, чтобы RequireRole и проверки
+// в хендлерах работали как для пользователей.
+func authenticateAPIKey(c *gin.Context, secret string) {
+	h := apiKeys.Load()
+	if h == nil || h.APIKeyAuthenticator == nil {
+		abortAuthScheme(c, schemeAPIKey, http.StatusUnauthorized, bearerInvalidToken, "api keys are not enabled")
+		return
+	}
+	key, err := h.AuthenticateAPIKey(c.Request.Context(), secret)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "api key verification failed", "component", "auth", logging.Err(err))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "api key verification unavailable"})
+		return
+	}
+	if key == nil {
+		abortAuthScheme(c, schemeAPIKey, http.StatusUnauthorized, bearerInvalidToken, "invalid, expired or revoked api key")
+		return
+	}
+
+	role := ""
+	switch {
+	case key.HasScope(models.APIKeyScopeAdmin):
+		role = RoleAdmin
+	case key.HasScope(models.APIKeyScopeApplicationsAll):
+		role = RoleStaff
+	}
+
+	c.Set("user_id", key.UserID)
+	c.Set("email", key.Email)
+	c.Set("role", role)
+	c.Set("api_key", key)
+	c.Request = c.Request.WithContext(logging.WithUserID(c.Request.Context(), key.UserID))
+	c.Next()
+}
+
+// RequireScope ограничивает запросы с API-ключом областями read (GET, HEAD) и write
+// (остальные методы). Запросы пользователей с JWT пропускаются: их права задают роли.
+// Подключается после AuthMiddleware.
+func RequireScope(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get("api_key")
+		if !ok {
+			c.Next()
+			return
+		}
+		scope := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = read
+		}
+		if !v.(*models.APIKey).HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key lacks scope " + scope})
+			return
+		}
+		c.Next()
+	}
+}
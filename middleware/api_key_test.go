@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shopflow/application/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyStub — APIKeyAuthenticator, который не знает ни одного ключа
+type apiKeyStub struct{}
+
+func (apiKeyStub) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	return nil, nil
+}
+
+func TestAuthMiddlewareAPIKeyRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { SetAPIKeyAuthenticator(nil) })
+
+	tests := []struct {
+		name          string
+		authenticator APIKeyAuthenticator
+		wantMessage   string
+	}{
+		{name: "api keys disabled", wantMessage: "api keys are not enabled"},
+		{name: "unknown key", authenticator: apiKeyStub{}, wantMessage: "invalid, expired or revoked api key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetAPIKeyAuthenticator(tt.authenticator)
+
+			r := gin.New()
+			r.GET("/", AuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(APIKeyHeader, "sk_unknown")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+			var body struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error != tt.wantMessage {
+				t.Errorf("error = %q, want %q", body.Error, tt.wantMessage)
+			}
+			want := `APIKey realm="shopflow", error="invalid_token", error_description="` + tt.wantMessage + `"`
+			if got := w.Header().Get("WWW-Authenticate"); got != want {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -1,67 +1,265 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
-	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+
+	"shopflow/application/jwks"
+	"shopflow/application/logging"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte(os.Getenv("SECRET_KEY"))
+// jwtSecret — ключ проверки подписи JWT; задаётся из конфигурации при запуске
+// (раньше читался из окружения при инициализации пакета, до загрузки .env)
+var jwtSecret atomic.Pointer[[]byte]
+
+// SetSecretKey задаёт ключ проверки подписи JWT
+func SetSecretKey(key string) {
+	b := []byte(key)
+	jwtSecret.Store(&b)
+}
+
+// jwtKeys — открытые ключи для RS*/PS*/ES*/EdDSA токенов (nil — принимаются только HS*)
+var jwtKeys atomic.Pointer[jwks.Keyring]
+
+// SetKeyring задаёт открытые ключи проверки асимметричных JWT (JWKS и PEM-файлы)
+func SetKeyring(k *jwks.Keyring) {
+	jwtKeys.Store(k)
+}
+
+// jwtAlgorithms — допустимые алгоритмы подписи; "none" и прочие отклоняются
+var jwtAlgorithms = []string{
+	"HS256", "HS384", "HS512",
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// RoleAdmin — роль из JWT (claim "role") с доступом к административным операциям
+const RoleAdmin = "admin"
+
+// RoleStaff — сотрудник бэк-офиса: видит заявки всех пользователей, но без админских операций
+const RoleStaff = "staff"
+
+// IsStaff — роль сотрудника (staff или admin)
+func IsStaff(role string) bool {
+	return role == RoleStaff || role == RoleAdmin
+}
+
+// Режимы проверки токена (AUTH_MODE)
+const (
+	// AuthModeLocal — только подпись и срок действия JWT
+	AuthModeLocal = "local"
+	// AuthModeRemote — только Auth сервис (VerifyToken); claims читаются без проверки подписи
+	AuthModeRemote = "remote"
+	// AuthModeBoth — локальная проверка, затем Auth сервис
+	AuthModeBoth = "both"
+)
+
+// TokenVerifier — удалённая проверка токена (services.AuthClient)
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, userID uint32, token string) (bool, string, error)
+}
+
+type authSettings struct {
+	mode     string
+	verifier TokenVerifier
+}
 
+var auth atomic.Pointer[authSettings]
+
+// SetAuthMode задаёт режим проверки токена; verifier нужен для режимов remote и both
+func SetAuthMode(mode string, verifier TokenVerifier) {
+	auth.Store(&authSettings{mode: mode, verifier: verifier})
+}
+
+func authMode() authSettings {
+	if s := auth.Load(); s != nil {
+		return *s
+	}
+	return authSettings{mode: AuthModeLocal}
+}
+
+// RevocationChecker — отзыв токенов до истечения срока (services.RevocationService)
+type RevocationChecker interface {
+	IsRevoked(userID uint, jti string, issuedAt time.Time) bool
+}
+
+type revocationHolder struct{ RevocationChecker }
+
+var revocations atomic.Pointer[revocationHolder]
+
+// SetRevocationChecker задаёт проверку отозванных токенов (nil — не проверяется)
+func SetRevocationChecker(r RevocationChecker) {
+	revocations.Store(&revocationHolder{r})
+}
+
+func isRevoked(claims *accessClaims) bool {
+	h := revocations.Load()
+	if h == nil || h.RevocationChecker == nil {
+		return false
+	}
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	return h.IsRevoked(uint(claims.UserID), claims.ID, issuedAt)
+}
+
+// AuthMiddleware проверяет Bearer-токен и кладёт в контекст user_id, email, role
+// и сам токен ("token") для вызовов, которым он нужен дальше. Отказ сопровождается
+// заголовком WWW-Authenticate с причиной. Вместо токена сервисы могут передать
+// API-ключ в X-API-Key.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			authenticateAPIKey(c, key)
+			return
+		}
+
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
+			abortAuth(c, http.StatusUnauthorized, "", "missing token")
 			return
 		}
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Проверяем алгоритм подписи
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		settings := authMode()
+		var claims accessClaims
+		var err error
+		if settings.mode == AuthModeRemote {
+			// подлинность токена подтвердит Auth сервис, claims проверяем сами
+			_, _, err = jwt.NewParser().ParseUnverified(tokenString, &claims)
+			if err == nil {
+				err = jwt.NewValidator(claimsOptions()...).Validate(&claims)
 			}
-			return []byte(os.Getenv("SECRET_KEY")), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		} else {
+			err = parseLocal(c.Request.Context(), tokenString, &claims)
+		}
+		if err == nil && claims.UserID == 0 {
+			err = errInvalidUserID
+		}
+		if err != nil {
+			abortAuth(c, http.StatusUnauthorized, bearerInvalidToken, describeTokenError(err))
 			return
 		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+		// до обращения к Auth: ответ о действительности токена мог остаться в кэше
+		if isRevoked(&claims) {
+			abortAuth(c, http.StatusUnauthorized, bearerInvalidToken, "token has been revoked")
 			return
 		}
+		userID := uint(claims.UserID)
+		email := claims.Email
 
-		// Поддержка user_id как float64 или string
-		var userID uint
-		switch v := claims["user_id"].(type) {
-		case float64:
-			userID = uint(v)
-		case string:
-			id, _ := strconv.Atoi(v)
-			userID = uint(id)
-		default:
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid user id in token"})
-			return
+		if settings.mode != AuthModeLocal {
+			if settings.verifier == nil {
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "auth service is not configured"})
+				return
+			}
+			valid, verifiedEmail, err := settings.verifier.VerifyToken(c.Request.Context(), uint32(userID), tokenString)
+			if err != nil {
+				slog.WarnContext(c.Request.Context(), "token verification failed", "component", "auth", logging.Err(err))
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "auth service unavailable"})
+				return
+			}
+			if !valid {
+				abortAuth(c, http.StatusUnauthorized, bearerInvalidToken, "token was rejected by auth service")
+				return
+			}
+			if verifiedEmail != "" {
+				email = verifiedEmail
+			}
 		}
 
-		email, ok := claims["email"].(string)
-		if !ok || email == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "email not found in token"})
+		if email == "" {
+			abortAuth(c, http.StatusUnauthorized, bearerInvalidToken, "email not found in token")
 			return
 		}
 
 		c.Set("user_id", userID)
 		c.Set("email", email)
+		c.Set("role", claims.Role)
+		c.Set("token", tokenString)
+		c.Request = c.Request.WithContext(logging.WithUserID(c.Request.Context(), userID))
+		c.Next()
+	}
+}
+
+// parseLocal проверяет подпись и claims JWT: HS* — общим секретом,
+// асимметричные алгоритмы — открытыми ключами (jwtKeys)
+func parseLocal(ctx context.Context, tokenString string, claims *accessClaims) error {
+	opts := append(claimsOptions(), jwt.WithValidMethods(jwtAlgorithms))
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return verificationKey(ctx, token)
+	}, opts...)
+	return err
+}
+
+// verificationKey подбирает ключ по алгоритму и kid токена. Тип ключа должен
+// соответствовать алгоритму: HMAC-токен нельзя проверить открытым ключом как секретом.
+func verificationKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		key := jwtSecret.Load()
+		if key == nil || len(*key) == 0 {
+			return nil, fmt.Errorf("jwt secret key is not configured")
+		}
+		return *key, nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+		ring := jwtKeys.Load()
+		if ring == nil {
+			return nil, fmt.Errorf("public keys are not configured")
+		}
+		kid, _ := token.Header["kid"].(string)
+		keys, err := ring.Lookup(ctx, kid, token.Method.Alg())
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 1 {
+			return keys[0], nil
+		}
+		set := jwt.VerificationKeySet{}
+		for _, k := range keys {
+			set.Keys = append(set.Keys, k)
+		}
+		return set, nil
+	}
+	return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+}
+
+// RequireRole пропускает только пользователей с одной из перечисленных ролей.
+// Подключается после AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		abortAuth(c, http.StatusForbidden, bearerInsufficientScope, "insufficient permissions")
+	}
+}
+
+// TokenFromQuery подставляет токен из query-параметра param в заголовок Authorization,
+// если заголовка нет. Нужен для клиентов, не умеющих задавать заголовки (EventSource, WebSocket).
+// Подключается перед AuthMiddleware.
+func TokenFromQuery(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query(param); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// accessClaims — claims токена доступа. Разбираются строго: неверный тип поля
+// делает токен недействительным, а не превращается в нулевое значение.
+type accessClaims struct {
+	jwt.RegisteredClaims
+	UserID userIDClaim `json:"user_id"`
+	Email  string      `json:"email"`
+	// Role необязательна: токены без неё считаются обычными пользователями
+	Role string `json:"role"`
+}
+
+var errInvalidUserID = errors.New("invalid user id in token")
+
+// userIDClaim — user_id: положительное целое числом или строкой из цифр ("42")
+type userIDClaim uint
+
+func (u *userIDClaim) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	// ID в Auth — uint32
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || id == 0 {
+		return errInvalidUserID
+	}
+	*u = userIDClaim(id)
+	return nil
+}
+
+// TokenValidation — проверки стандартных claims. exp обязателен всегда;
+// nbf и iat проверяются, если есть.
+type TokenValidation struct {
+	// Issuer — ожидаемый iss (пусто — не проверяется)
+	Issuer string
+	// Audience — токен должен быть выдан хотя бы для одной из них (пусто — не проверяется)
+	Audience []string
+	// Leeway — допуск расхождения часов для exp, nbf и iat
+	Leeway time.Duration
+}
+
+var tokenValidation atomic.Pointer[TokenValidation]
+
+// SetTokenValidation задаёт проверки стандартных claims
+func SetTokenValidation(v TokenValidation) {
+	tokenValidation.Store(&v)
+}
+
+func claimsOptions() []jwt.ParserOption {
+	opts := []jwt.ParserOption{jwt.WithExpirationRequired(), jwt.WithIssuedAt()}
+	v := tokenValidation.Load()
+	if v == nil {
+		return opts
+	}
+	opts = append(opts, jwt.WithLeeway(v.Leeway))
+	if v.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.Issuer))
+	}
+	if len(v.Audience) > 0 {
+		opts = append(opts, jwt.WithAudience(v.Audience...))
+	}
+	return opts
+}
+
+// describeTokenError — понятная клиенту причина отказа (error_description)
+func describeTokenError(err error) string {
+	switch {
+	case errors.Is(err, errInvalidUserID):
+		return "invalid user id in token"
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "token is expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return "token is not valid yet"
+	case errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "token is issued in the future"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "token has invalid issuer"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "token has invalid audience"
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return "token is missing a required claim (exp, iss or aud)"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return "token signature is invalid"
+	case errors.Is(err, jwt.ErrTokenUnverifiable):
+		return "token signing key or algorithm is not accepted"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "token is malformed"
+	}
+	return "invalid token"
+}
+
+// authRealm — realm в заголовке WWW-Authenticate
+const authRealm = "shopflow"
+
+// Коды ошибок RFC 6750 для WWW-Authenticate
+const (
+	bearerInvalidToken      = "invalid_token"
+	bearerInsufficientScope = "insufficient_scope"
+)
+
+// Схемы аутентификации в WWW-Authenticate
+const (
+	schemeBearer = "Bearer"
+	schemeAPIKey = "APIKey"
+)
+
+// abortAuth отвечает status с заголовком WWW-Authenticate (RFC 6750). Без errorCode
+// (токен не передан) указывается только схема — клиенту достаточно знать, что нужен токен.
+func abortAuth(c *gin.Context, status int, errorCode, description string) {
+	abortAuthScheme(c, schemeBearer, status, errorCode, description)
+}
+
+// abortAuthScheme — abortAuth с указанной схемой: для X-API-Key вызов идёт с APIKey,
+// чтобы клиент не пытался повторить запрос с Bearer-токеном.
+func abortAuthScheme(c *gin.Context, scheme string, status int, errorCode, description string) {
+	challenge := fmt.Sprintf("%s realm=%q", scheme, authRealm)
+	if errorCode != "" {
+		challenge += fmt.Sprintf(", error=%q, error_description=%q", errorCode, description)
+	}
+	c.Header("WWW-Authenticate", challenge)
+	c.AbortWithStatusJSON(status, gin.H{"error": description})
+}
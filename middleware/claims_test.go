@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func signTestToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// validClaims — claims, с которыми токен проходит проверку; тест меняет одно поле
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"user_id": 42,
+		"email":   "user@example.com",
+		"iss":     "auth",
+		"aud":     "applications",
+		"iat":     now.Unix(),
+		"exp":     now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestAuthMiddlewareClaims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetSecretKey(testSecret)
+	SetAuthMode(AuthModeLocal, nil)
+	SetTokenValidation(TokenValidation{Issuer: "auth", Audience: []string{"applications"}, Leeway: time.Second})
+	t.Cleanup(func() { SetTokenValidation(TokenValidation{}) })
+
+	tests := []struct {
+		name        string
+		change      func(jwt.MapClaims)
+		wantStatus  int
+		wantUserID  uint
+		wantMessage string
+	}{
+		{name: "user_id as number", change: func(m jwt.MapClaims) {}, wantStatus: http.StatusOK, wantUserID: 42},
+		{name: "user_id as digit string", change: func(m jwt.MapClaims) { m["user_id"] = "42" }, wantStatus: http.StatusOK, wantUserID: 42},
+		{name: "user_id as text", change: func(m jwt.MapClaims) { m["user_id"] = "abc" }, wantStatus: http.StatusUnauthorized, wantMessage: "invalid user id in token"},
+		{name: "user_id zero", change: func(m jwt.MapClaims) { m["user_id"] = 0 }, wantStatus: http.StatusUnauthorized, wantMessage: "invalid user id in token"},
+		{name: "user_id negative", change: func(m jwt.MapClaims) { m["user_id"] = -1 }, wantStatus: http.StatusUnauthorized, wantMessage: "invalid user id in token"},
+		{name: "user_id fractional", change: func(m jwt.MapClaims) { m["user_id"] = 1.5 }, wantStatus: http.StatusUnauthorized, wantMessage: "invalid user id in token"},
+		{name: "user_id above uint32", change: func(m jwt.MapClaims) { m["user_id"] = 1 << 32 }, wantStatus: http.StatusUnauthorized, wantMessage: "invalid user id in token"},
+		{name: "user_id null", change: func(m jwt.MapClaims) { m["user_id"] = nil }, wantStatus: http.StatusUnauthorized, wantMessage: "invalid user id in token"},
+		{name: "user_id missing", change: func(m jwt.MapClaims) { delete(m, "user_id") }, wantStatus: http.StatusUnauthorized, wantMessage: "invalid user id in token"},
+		{name: "exp missing", change: func(m jwt.MapClaims) { delete(m, "exp") }, wantStatus: http.StatusUnauthorized, wantMessage: "token is missing a required claim (exp, iss or aud)"},
+		{name: "exp in the past", change: func(m jwt.MapClaims) { m["exp"] = time.Now().Add(-time.Minute).Unix() }, wantStatus: http.StatusUnauthorized, wantMessage: "token is expired"},
+		{name: "exp within leeway", change: func(m jwt.MapClaims) { m["exp"] = time.Now().Unix() }, wantStatus: http.StatusOK, wantUserID: 42},
+		{name: "iat in the future", change: func(m jwt.MapClaims) { m["iat"] = time.Now().Add(time.Minute).Unix() }, wantStatus: http.StatusUnauthorized, wantMessage: "token is issued in the future"},
+		{name: "wrong iss", change: func(m jwt.MapClaims) { m["iss"] = "someone-else" }, wantStatus: http.StatusUnauthorized, wantMessage: "token has invalid issuer"},
+		{name: "iss missing", change: func(m jwt.MapClaims) { delete(m, "iss") }, wantStatus: http.StatusUnauthorized, wantMessage: "token is missing a required claim (exp, iss or aud)"},
+		{name: "wrong aud", change: func(m jwt.MapClaims) { m["aud"] = "billing" }, wantStatus: http.StatusUnauthorized, wantMessage: "token has invalid audience"},
+		{name: "aud list with ours", change: func(m jwt.MapClaims) { m["aud"] = []string{"billing", "applications"} }, wantStatus: http.StatusOK, wantUserID: 42},
+		{name: "aud missing", change: func(m jwt.MapClaims) { delete(m, "aud") }, wantStatus: http.StatusUnauthorized, wantMessage: "token is missing a required claim (exp, iss or aud)"},
+		{name: "email missing", change: func(m jwt.MapClaims) { delete(m, "email") }, wantStatus: http.StatusUnauthorized, wantMessage: "email not found in token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.change(claims)
+
+			var gotUserID uint
+			r := gin.New()
+			r.GET("/", AuthMiddleware(), func(c *gin.Context) {
+				gotUserID = c.GetUint("user_id")
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+signTestToken(t, claims))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if gotUserID != tt.wantUserID {
+					t.Errorf("user_id = %d, want %d", gotUserID, tt.wantUserID)
+				}
+				return
+			}
+
+			var body struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error != tt.wantMessage {
+				t.Errorf("error = %q, want %q", body.Error, tt.wantMessage)
+			}
+			challenge := w.Header().Get("WWW-Authenticate")
+			if !strings.Contains(challenge, `error="invalid_token"`) {
+				t.Errorf("WWW-Authenticate = %q, want error=invalid_token", challenge)
+			}
+		})
+	}
+}
+
+func TestUserIDClaimUnmarshal(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    userIDClaim
+		wantErr bool
+	}{
+		{raw: `42`, want: 42},
+		{raw: `"42"`, want: 42},
+		{raw: `4294967295`, want: 4294967295},
+		{raw: `"abc"`, wantErr: true},
+		{raw: `""`, wantErr: true},
+		{raw: `0`, wantErr: true},
+		{raw: `"0"`, wantErr: true},
+		{raw: `-1`, wantErr: true},
+		{raw: `1.5`, wantErr: true},
+		{raw: `1e3`, wantErr: true},
+		{raw: `4294967296`, wantErr: true},
+		{raw: `null`, wantErr: true},
+		{raw: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			var got userIDClaim
+			err := got.UnmarshalJSON([]byte(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("user_id = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"shopflow/application/logging"
+	"shopflow/application/repository"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// responseRecorder дублирует тело ответа в буфер, чтобы его можно было сохранить
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// IdempotencyMiddleware обрабатывает заголовок Idempotency-Key: первый запрос
+// выполняется и его успешный ответ сохраняется на ttl, повторы с тем же телом
+// получают сохранённый ответ, а повтор ключа с другим телом — 422.
+// Должен подключаться после AuthMiddleware: ключи изолированы по user_id.
+func IdempotencyMiddleware(repo *repository.IdempotencyRepository, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" || repo == nil {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.FullPath()+"\n"), body...))
+		requestHash := hex.EncodeToString(sum[:])
+		userID := c.GetUint("user_id")
+
+		reserved, err := repo.Reserve(c.Request.Context(), userID, key, requestHash, ttl)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !reserved {
+			replayIdempotentResponse(c, repo, userID, key, requestHash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		// Ключ фиксируем или освобождаем и при панике в обработчике: иначе он останется
+		// занятым до истечения ttl. Панику не перехватываем, чтобы Recovery получил
+		// исходный стек, а запрос считаем завершившимся с 5xx.
+		finished := false
+		defer func() {
+			status := recorder.Status()
+			if !finished {
+				status = http.StatusInternalServerError
+			}
+			finishIdempotentRequest(c.Request.Context(), repo, userID, key, status, recorder.body.Bytes())
+		}()
+		c.Next()
+		finished = true
+	}
+}
+
+// finishIdempotentRequest сохраняет успешный ответ или освобождает ключ.
+// Результат фиксируем, даже если клиент уже отключился: иначе ключ останется занятым.
+func finishIdempotentRequest(ctx context.Context, repo *repository.IdempotencyRepository, userID uint, key string, status int, body []byte) {
+	ctx = context.WithoutCancel(ctx)
+	if status >= 200 && status < 300 {
+		if err := repo.Complete(ctx, userID, key, status, body); err != nil {
+			slog.ErrorContext(ctx, "failed to store idempotent response", logging.Err(err))
+		}
+		return
+	}
+	// Неуспешный запрос не фиксируем — клиент может повторить его с тем же ключом
+	if err := repo.Release(ctx, userID, key); err != nil {
+		slog.ErrorContext(ctx, "failed to release idempotency key", logging.Err(err))
+	}
+}
+
+func replayIdempotentResponse(c *gin.Context, repo *repository.IdempotencyRepository, userID uint, key, requestHash string) {
+	rec, err := repo.Get(c.Request.Context(), userID, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		// исходный запрос только что завершился неуспешно и освободил ключ
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key has just failed, retry it"})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rec.RequestHash != requestHash {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+		return
+	}
+	if rec.StatusCode == 0 {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is still in progress"})
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(rec.StatusCode, "application/json; charset=utf-8", rec.ResponseBody)
+	c.Abort()
+}
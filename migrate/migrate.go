@@ -0,0 +1,318 @@
+// Package migrate применяет встроенные SQL-миграции. Состояние хранится в таблице
+// schema_migrations в том же формате, что у golang-migrate (одна строка: version, dirty),
+// поэтому базы, размеченные этой утилитой, продолжают работать.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// lockKey — ключ advisory lock, под которым реплики по очереди применяют миграции
+const lockKey int64 = 0x73686f70666c6f77 // "shopflow"
+
+// ErrDirty — предыдущая миграция упала посередине; состояние нужно проверить
+// вручную и отметить командой force
+var ErrDirty = errors.New("database is in dirty state")
+
+// ErrUnknownVersion — в наборе миграций нет такой версии
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration — пара up/down одной версии
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status — состояние схемы
+type Status struct {
+	// Current — последняя применённая версия (0 — ничего не применено)
+	Current uint
+	Dirty   bool
+	Latest  uint
+	// Pending — версии, которые будут применены командой up
+	Pending []uint
+}
+
+// Migrator применяет миграции к Postgres
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// Load читает миграции из fsys и проверяет, что у каждой версии есть непустые up и down
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		v, err := strconv.ParseUint(m[1], 10, 32)
+		if err != nil || v == 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", e.Name())
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[uint(v)]
+		if !ok {
+			mig = &Migration{Version: uint(v), Name: m[2]}
+			byVersion[uint(v)] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", v, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have non-empty up and down files", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// New создаёт мигратор для миграций из fsys
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Latest — последняя версия в наборе
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status — текущая версия схемы и ожидающие миграции
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	var st Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		var err error
+		st.Current, st.Dirty, err = readVersion(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return st, err
+	}
+	st.Latest = m.Latest()
+	for _, mig := range m.migrations {
+		if mig.Version > st.Current {
+			st.Pending = append(st.Pending, mig.Version)
+		}
+	}
+	return st, nil
+}
+
+// Up применяет все ещё не применённые миграции
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down откатывает steps последних миграций
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive")
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w at version %d", ErrDirty, current)
+		}
+		target := uint(0)
+		applied := m.appliedUpTo(current)
+		if steps < len(applied) {
+			target = applied[len(applied)-steps-1].Version
+		}
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// To приводит схему к версии target (вверх или вниз); 0 — откатить всё
+func (m *Migrator) To(ctx context.Context, target uint) error {
+	if target != 0 && m.find(target) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w at version %d", ErrDirty, current)
+		}
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// Force записывает версию без выполнения миграций и снимает флаг dirty
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if version != 0 && m.find(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := writeVersion(ctx, tx, version); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+func (m *Migrator) find(version uint) int {
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// appliedUpTo — миграции с версией не выше current по возрастанию
+func (m *Migrator) appliedUpTo(current uint) []Migration {
+	var applied []Migration
+	for _, mig := range m.migrations {
+		if mig.Version <= current {
+			applied = append(applied, mig)
+		}
+	}
+	return applied
+}
+
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, current, target uint) error {
+	if current != 0 && m.find(current) < 0 {
+		return fmt.Errorf("%w: database is at version %d which is not in this binary", ErrUnknownVersion, current)
+	}
+
+	if target >= current {
+		for _, mig := range m.migrations {
+			if mig.Version <= current || mig.Version > target {
+				continue
+			}
+			slog.Info("applying migration", "component", "migrate", "version", mig.Version, "name", mig.Name)
+			if err := m.apply(ctx, conn, mig.Up, mig.Version); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+			}
+		}
+		return nil
+	}
+
+	applied := m.appliedUpTo(current)
+	for i := len(applied) - 1; i >= 0 && applied[i].Version > target; i-- {
+		mig := applied[i]
+		prev := uint(0)
+		if i > 0 {
+			prev = applied[i-1].Version
+		}
+		slog.Info("reverting migration", "component", "migrate", "version", mig.Version, "name", mig.Name)
+		if err := m.apply(ctx, conn, mig.Down, prev); err != nil {
+			return fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+		}
+	}
+	return nil
+}
+
+// apply выполняет SQL миграции и записывает новую версию в одной транзакции:
+// DDL в Postgres транзакционен, поэтому упавшая миграция не оставляет схему
+// в промежуточном состоянии (dirty может остаться только от golang-migrate).
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, query string, newVersion uint) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	if err := writeVersion(ctx, tx, newVersion); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// withLock берёт advisory lock на отдельном соединении, создаёт таблицу версий и выполняет fn
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if _, err := conn.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations
+        (
+            version BIGINT  NOT NULL PRIMARY KEY,
+            dirty   BOOLEAN NOT NULL
+        )`); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func readVersion(ctx context.Context, conn *sql.Conn) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
+
+func writeVersion(ctx context.Context, tx *sql.Tx, version uint) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)`, version)
+	return err
+}
@@ -0,0 +1,2 @@
+ALTER TABLE user_applications
+    DROP COLUMN IF EXISTS version;
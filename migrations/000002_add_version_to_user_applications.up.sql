@@ -0,0 +1,2 @@
+ALTER TABLE user_applications
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys
+(
+    user_id       INT          NOT NULL,
+    key           VARCHAR(255) NOT NULL,
+    request_hash  CHAR(64)     NOT NULL,
+    status_code   INT,
+    response_body BYTEA,
+    created_at    TIMESTAMP    NOT NULL DEFAULT NOW(),
+    expires_at    TIMESTAMP    NOT NULL,
+    PRIMARY KEY (user_id, key)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
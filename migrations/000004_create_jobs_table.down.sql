@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_applications_status_created_at;
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS jobs
+(
+    id               SERIAL PRIMARY KEY,
+    type             VARCHAR(50) NOT NULL,
+    status           VARCHAR(20) NOT NULL DEFAULT 'pending',
+    params           JSONB       NOT NULL DEFAULT '{}',
+    result           JSONB,
+    total            INT         NOT NULL DEFAULT 0,
+    processed        INT         NOT NULL DEFAULT 0,
+    error            TEXT,
+    cancel_requested BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_by       INT         NOT NULL,
+    created_at       TIMESTAMP   NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMP   NOT NULL DEFAULT NOW(),
+    finished_at      TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_user_applications_status_created_at ON user_applications (status, created_at);
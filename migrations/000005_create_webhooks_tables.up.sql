@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions
+(
+    id                   SERIAL PRIMARY KEY,
+    url                  VARCHAR(2048) NOT NULL,
+    event_types          TEXT[]        NOT NULL,
+    secret               VARCHAR(128)  NOT NULL,
+    active               BOOLEAN       NOT NULL DEFAULT TRUE,
+    consecutive_failures INT           NOT NULL DEFAULT 0,
+    disabled_reason      TEXT,
+    created_by           INT           NOT NULL,
+    created_at           TIMESTAMP     NOT NULL DEFAULT NOW(),
+    updated_at           TIMESTAMP     NOT NULL DEFAULT NOW()
+    );
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id               SERIAL PRIMARY KEY,
+    subscription_id  INT         NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_type       VARCHAR(100) NOT NULL,
+    payload          JSONB       NOT NULL,
+    status           VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts         INT         NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMP   NOT NULL DEFAULT NOW(),
+    last_status_code INT,
+    last_error       TEXT,
+    last_response    TEXT,
+    created_at       TIMESTAMP   NOT NULL DEFAULT NOW(),
+    delivered_at     TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, created_at DESC);
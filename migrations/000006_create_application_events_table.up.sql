@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS application_events
+(
+    id         BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    user_id    INT,
+    payload    JSONB        NOT NULL,
+    created_at TIMESTAMP    NOT NULL DEFAULT NOW()
+    );
+
+CREATE INDEX IF NOT EXISTS idx_application_events_user ON application_events (user_id, id);
+CREATE INDEX IF NOT EXISTS idx_application_events_created_at ON application_events (created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_user_applications_assignee;
+
+ALTER TABLE user_applications DROP COLUMN IF EXISTS assignee_id;
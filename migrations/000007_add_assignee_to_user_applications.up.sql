@@ -0,0 +1,3 @@
+ALTER TABLE user_applications ADD COLUMN IF NOT EXISTS assignee_id INT;
+
+CREATE INDEX IF NOT EXISTS idx_user_applications_assignee ON user_applications (assignee_id) WHERE assignee_id IS NOT NULL;
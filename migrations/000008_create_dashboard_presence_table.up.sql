@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS dashboard_presence
+(
+    application_id INT          NOT NULL,
+    session_id     VARCHAR(64)  NOT NULL,
+    user_id        INT          NOT NULL,
+    email          VARCHAR(255) NOT NULL DEFAULT '',
+    since          TIMESTAMP    NOT NULL DEFAULT NOW(),
+    seen_at        TIMESTAMP    NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (application_id, session_id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_dashboard_presence_seen_at ON dashboard_presence (seen_at);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS revoked_tokens
+(
+    jti        VARCHAR(255) PRIMARY KEY,
+    user_id    INT          NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP    NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP    NOT NULL
+    );
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+-- токены пользователя, выданные раньше issued_before, недействительны (выход на всех устройствах)
+CREATE TABLE IF NOT EXISTS user_token_cutoffs
+(
+    user_id       INT       PRIMARY KEY,
+    issued_before TIMESTAMP NOT NULL
+    );
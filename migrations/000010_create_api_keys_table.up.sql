@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS api_keys
+(
+    id           SERIAL PRIMARY KEY,
+    name         VARCHAR(255) NOT NULL,
+    prefix       VARCHAR(16)  NOT NULL,
+    key_hash     CHAR(64)     NOT NULL UNIQUE,
+    user_id      INT          NOT NULL,
+    email        VARCHAR(255) NOT NULL,
+    scopes       TEXT[]       NOT NULL,
+    created_by   INT          NOT NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at   TIMESTAMP
+    );
@@ -0,0 +1,8 @@
+-- файлы фоновых выгрузок хранятся в базе частями, чтобы их могла отдать любая реплика
+CREATE TABLE IF NOT EXISTS export_chunks
+(
+    job_id INT   NOT NULL REFERENCES jobs (id) ON DELETE CASCADE,
+    seq    INT   NOT NULL,
+    data   BYTEA NOT NULL,
+    PRIMARY KEY (job_id, seq)
+    );
@@ -0,0 +1,8 @@
+// Package migrations — SQL-миграции схемы, встроенные в бинарник.
+// Файлы именуются как в golang-migrate: <версия>_<название>.up.sql / .down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Области доступа API-ключей
+const (
+	// APIKeyScopeApplicationsRead — чтение и выгрузка заявок
+	APIKeyScopeApplicationsRead = "applications:read"
+	// APIKeyScopeApplicationsWrite — создание, изменение и удаление заявок
+	APIKeyScopeApplicationsWrite = "applications:write"
+	// APIKeyScopeApplicationsAll — заявки всех пользователей, как у сотрудника (роль staff)
+	APIKeyScopeApplicationsAll = "applications:all"
+	// APIKeyScopeAdmin — всё, включая /api/admin (роль admin)
+	APIKeyScopeAdmin = "admin"
+)
+
+// APIKey — ключ для вызовов REST API внутренними сервисами (пакетные задачи и т. п.)
+// без пользовательского JWT. Запросы с ключом выполняются от имени UserID/Email.
+// Хранится только хэш; сам ключ (Key) возвращается один раз — при выпуске.
+type APIKey struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	// Prefix — начало ключа, чтобы отличать ключи в списке
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty"`
+	UserID     uint       `json:"user_id"`
+	Email      string     `json:"email"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  uint       `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope — у ключа есть область scope (admin включает все)
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == APIKeyScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+	// UserID и Email — учётная запись, от имени которой работает ключ
+	UserID uint     `json:"user_id" binding:"required"`
+	Email  string   `json:"email" binding:"required,email,max=255"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// ExpiresAt — срок действия (пусто — бессрочный)
+	ExpiresAt *time.Time `json:"expires_at"`
+}
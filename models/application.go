@@ -8,8 +8,15 @@ type Application struct {
 	Text      string
 	FileURL   string
 	Status    string
+	Version   int
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// AssigneeID — сотрудник, ведущий заявку (nil — не назначена)
+	AssigneeID *uint
+	// PreviousStatus и PreviousAssigneeID — значения до изменения; заполняются только
+	// UpdateApplication и SetStatusByFilter (пустой PreviousStatus — неизвестны)
+	PreviousStatus     string `json:"-"`
+	PreviousAssigneeID *uint  `json:"-"`
 }
 
 type CreateApplicationRequest struct {
@@ -18,8 +25,17 @@ type CreateApplicationRequest struct {
 	FileURL string `json:"file_url"  binding:"required"`
 }
 
+// UpdateApplicationRequest — частичное обновление заявки (PATCH).
+// Изменяются только переданные поля; nil означает «оставить как есть».
 type UpdateApplicationRequest struct {
-	Text    string `json:"text"`
-	Status  string `json:"status"`
-	FileURL string `json:"file_url"`
+	Text    *string `json:"text"`
+	Status  *string `json:"status"`
+	FileURL *string `json:"file_url"`
+	// AssigneeID — назначить сотрудника (только для staff); 0 снимает назначение
+	AssigneeID *uint `json:"assignee_id"`
+}
+
+// IsEmpty — в запросе нет ни одного поля для обновления
+func (r UpdateApplicationRequest) IsEmpty() bool {
+	return r.Text == nil && r.Status == nil && r.FileURL == nil && r.AssigneeID == nil
 }
@@ -0,0 +1,52 @@
+package models
+
+// Операции пакетного запроса
+const (
+	BatchOpCreate     = "create"
+	BatchOpUpdate     = "update"
+	BatchOpTransition = "transition"
+	BatchOpDelete     = "delete"
+)
+
+// Режимы выполнения пакетного запроса
+const (
+	// BatchModeAtomic — все операции в одной транзакции: любая ошибка откатывает весь пакет
+	BatchModeAtomic = "atomic"
+	// BatchModeIndependent — каждая операция выполняется отдельно, результат по каждой
+	BatchModeIndependent = "independent"
+)
+
+// BatchOperation — одна операция пакетного запроса.
+// create: text, file_url, status; update: id и любые из text, file_url, status, assignee_id
+// (assignee_id — только сотрудники, 0 снимает назначение);
+// transition: id и status; delete: id. Version (необязательно) — ожидаемая версия заявки.
+type BatchOperation struct {
+	Op         string  `json:"op" binding:"required,oneof=create update transition delete"`
+	ID         uint    `json:"id"`
+	Version    int     `json:"version"`
+	Text       *string `json:"text"`
+	Status     *string `json:"status"`
+	FileURL    *string `json:"file_url"`
+	AssigneeID *uint   `json:"assignee_id"`
+}
+
+type BatchRequest struct {
+	Mode       string           `json:"mode" binding:"omitempty,oneof=atomic independent"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,dive"`
+}
+
+// BatchResult — результат одной операции; Status — HTTP-код, который вернул бы одиночный запрос
+type BatchResult struct {
+	Index       int          `json:"index"`
+	Op          string       `json:"op"`
+	Status      int          `json:"status"`
+	Application *Application `json:"application,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
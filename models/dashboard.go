@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Типы сообщений клиента WebSocket-канала дашбордов
+const (
+	DashboardSubscribe   = "subscribe"
+	DashboardUnsubscribe = "unsubscribe"
+	DashboardView        = "view"
+	DashboardLeave       = "leave"
+	DashboardPing        = "ping"
+)
+
+// Типы сообщений сервера
+const (
+	DashboardEvent      = "event"
+	DashboardPresence   = "presence"
+	DashboardSubscribed = "subscribed"
+	DashboardPong       = "pong"
+	DashboardError      = "error"
+)
+
+// DashboardClientMessage — сообщение от клиента.
+// Темы: "all", "status:<status>", "assignee:<id>", "assignee:me", "unassigned".
+type DashboardClientMessage struct {
+	Type          string   `json:"type"`
+	Topics        []string `json:"topics,omitempty"`
+	ApplicationID uint     `json:"application_id,omitempty"`
+}
+
+// DashboardServerMessage — сообщение сервера клиенту
+type DashboardServerMessage struct {
+	Type          string            `json:"type"`
+	Topics        []string          `json:"topics,omitempty"`
+	Event         *ApplicationEvent `json:"event,omitempty"`
+	ApplicationID uint              `json:"application_id,omitempty"`
+	Viewers       []PresenceViewer  `json:"viewers,omitempty"`
+	Error         string            `json:"error,omitempty"`
+}
+
+// PresenceViewer — сотрудник, который сейчас открыл заявку
+type PresenceViewer struct {
+	UserID uint      `json:"user_id"`
+	Email  string    `json:"email"`
+	Since  time.Time `json:"since"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ApplicationEvent — запись журнала событий заявок, который отдаётся клиентам через SSE.
+// ID монотонно растёт и используется как Last-Event-ID для продолжения потока.
+// UserID == 0 — событие без владельца (видно только сотрудникам).
+type ApplicationEvent struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	UserID    uint            `json:"user_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// ExportOptions — параметры выгрузки заявок
+type ExportOptions struct {
+	Format   string   `json:"format"`
+	Columns  []string `json:"columns,omitempty"`
+	Locale   string   `json:"locale,omitempty"`
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+// ExportJobParams — параметры фоновой задачи export, сохраняемые в jobs.params
+type ExportJobParams struct {
+	Filter  ApplicationFilter `json:"filter"`
+	Options ExportOptions     `json:"options"`
+}
+
+// ExportJobResult — результат фоновой выгрузки: файл доступен через /api/jobs/{id}/download
+type ExportJobResult struct {
+	Rows        int    `json:"rows"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
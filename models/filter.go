@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ApplicationFilter — фильтры выборки заявок (список, массовые операции, экспорт).
+// Нулевые значения полей означают «без ограничения».
+type ApplicationFilter struct {
+	UserID        uint       `json:"user_id,omitempty"`
+	Status        string     `json:"status,omitempty"`
+	ExcludeStatus string     `json:"exclude_status,omitempty"`
+	AssigneeID    uint       `json:"assignee_id,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	// MaxID — верхняя граница ID (снимок выборки на момент запуска фоновой задачи)
+	MaxID uint `json:"max_id,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotencyRecord — сохранённый результат запроса с заголовком Idempotency-Key.
+// StatusCode == 0 означает, что исходный запрос ещё выполняется.
+type IdempotencyRecord struct {
+	UserID       uint
+	Key          string
+	RequestHash  string
+	StatusCode   int
+	ResponseBody []byte
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,34 @@
+package models
+
+// ImportOptions — параметры массовой загрузки заявок
+type ImportOptions struct {
+	Format string
+	// PreserveTimestamps — брать created_at/updated_at из файла (иначе NOW())
+	PreserveTimestamps bool
+	// PreserveStatus — брать status из файла (иначе "new")
+	PreserveStatus bool
+	// SuppressEvents — не публиковать application_created для загруженных заявок
+	SuppressEvents bool
+	// SkipInvalid — загрузить валидные строки, даже если в файле есть ошибки
+	SkipInvalid bool
+	// DryRun — только проверить файл, ничего не записывая
+	DryRun bool
+}
+
+// ImportLineError — ошибка в конкретной строке файла
+type ImportLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportReport — итог загрузки
+type ImportReport struct {
+	DryRun    bool `json:"dry_run"`
+	TotalRows int  `json:"total_rows"`
+	Valid     int  `json:"valid"`
+	Failed    int  `json:"failed"`
+	Imported  int  `json:"imported"`
+	// Errors содержит не больше первых 1000 ошибок; ErrorsTruncated — были и другие
+	Errors          []ImportLineError `json:"errors"`
+	ErrorsTruncated bool              `json:"errors_truncated,omitempty"`
+}
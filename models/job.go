@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы фоновых задач
+const (
+	JobTypeBulkStatusChange = "bulk_status_change"
+	JobTypeExport           = "export"
+)
+
+// Статусы фоновых задач
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// Job — фоновая задача. Состояние хранится в БД, поэтому прогресс
+// и отмена доступны с любой реплики сервиса.
+type Job struct {
+	ID              uint            `json:"id"`
+	Type            string          `json:"type"`
+	Status          string          `json:"status"`
+	Params          json.RawMessage `json:"params" swaggertype:"object"`
+	Result          json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	Total           int             `json:"total"`
+	Processed       int             `json:"processed"`
+	Error           string          `json:"error,omitempty"`
+	CancelRequested bool            `json:"cancel_requested"`
+	CreatedBy       uint            `json:"created_by"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty"`
+}
+
+// Finished — задача завершилась (успешно, с ошибкой или отменена)
+func (j *Job) Finished() bool {
+	return j.Status == JobStatusSucceeded || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
+
+// BulkStatusChangeRequest — массовая смена статуса заявок; фильтры передаются
+// query-параметрами, как у GET /api/applications
+type BulkStatusChangeRequest struct {
+	TargetStatus string `json:"target_status" binding:"required,max=20"`
+}
+
+// BulkStatusChangeParams — параметры задачи bulk_status_change, сохраняемые в jobs.params
+type BulkStatusChangeParams struct {
+	Filter       ApplicationFilter `json:"filter"`
+	TargetStatus string            `json:"target_status"`
+}
+
+// BulkStatusChangePreview — ответ dry-run: сколько заявок будет затронуто и примеры ID
+type BulkStatusChangePreview struct {
+	DryRun    bool   `json:"dry_run"`
+	Count     int    `json:"count"`
+	SampleIDs []uint `json:"sample_ids"`
+}
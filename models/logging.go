@@ -0,0 +1,6 @@
+package models
+
+// LogLevel — уровень логирования реплики: debug, info, warn или error
+type LogLevel struct {
+	Level string `json:"level" binding:"required" example:"debug"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RevokedToken — отозванный до истечения срока токен (по claim jti).
+// Запись нужна, пока токен мог бы пройти проверку, то есть до ExpiresAt.
+type RevokedToken struct {
+	JTI       string
+	UserID    uint
+	ExpiresAt time.Time
+}
+
+// UserTokenCutoff — все токены пользователя, выданные раньше IssuedBefore (claim iat),
+// недействительны: пользователь вышел на всех устройствах или сменил пароль
+type UserTokenCutoff struct {
+	UserID       uint
+	IssuedBefore time.Time
+}
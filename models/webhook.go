@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEventAll — подписка на все типы событий
+const WebhookEventAll = "*"
+
+// WebhookEventTest — тип тестового события, отправляемого по запросу
+const WebhookEventTest = "webhook.test"
+
+// Статусы доставки вебхука
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookSubscription — подписка партнёра на события заявок.
+// Secret возвращается только при создании подписки.
+type WebhookSubscription struct {
+	ID                  uint      `json:"id"`
+	URL                 string    `json:"url"`
+	EventTypes          []string  `json:"event_types"`
+	Secret              string    `json:"secret,omitempty"`
+	Active              bool      `json:"active"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	DisabledReason      string    `json:"disabled_reason,omitempty"`
+	CreatedBy           uint      `json:"created_by"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// Matches — подписка активна и получает события этого типа
+func (s *WebhookSubscription) Matches(eventType string) bool {
+	if !s.Active {
+		return false
+	}
+	for _, t := range s.EventTypes {
+		if t == WebhookEventAll || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url,max=2048"`
+	EventTypes []string `json:"event_types" binding:"required,min=1"`
+	// Secret необязателен: если не передан, генерируется случайный
+	Secret string `json:"secret" binding:"omitempty,min=16,max=128"`
+}
+
+// UpdateWebhookRequest — частичное обновление подписки. Active=true
+// повторно включает подписку, отключённую из-за ошибок.
+type UpdateWebhookRequest struct {
+	URL        *string  `json:"url" binding:"omitempty,url,max=2048"`
+	EventTypes []string `json:"event_types"`
+	Secret     *string  `json:"secret" binding:"omitempty,min=16,max=128"`
+	Active     *bool    `json:"active"`
+}
+
+// WebhookDelivery — попытки доставки одного события одной подписке (журнал доставок)
+type WebhookDelivery struct {
+	ID             uint            `json:"id"`
+	SubscriptionID uint            `json:"subscription_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	LastResponse   string          `json:"last_response,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// WebhookEvent — тело HTTP-запроса, отправляемого подписчику
+type WebhookEvent struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data" swaggertype:"object"`
+}
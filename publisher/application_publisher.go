@@ -1,14 +1,21 @@
 package publisher
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
+	"shopflow/application/logging"
+	"shopflow/application/metrics"
+	"shopflow/application/tracing"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type ApplicationPublisher struct {
 	MQConn *amqp.Connection
+	// PublishTimeout ограничивает отправку одного события (0 — без ограничения)
+	PublishTimeout time.Duration
 }
 
 type ApplicationCreatedMessage struct {
@@ -16,13 +23,47 @@ type ApplicationCreatedMessage struct {
 	UserID uint   `json:"user_id"`
 	Text   string `json:"text"`
 	File   string `json:"file_url"`
+	// RequestID — X-Request-ID запроса, породившего событие
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func NewApplicationPublisher(conn *amqp.Connection) *ApplicationPublisher {
 	return &ApplicationPublisher{MQConn: conn}
 }
 
-func (p *ApplicationPublisher) PublishApplicationCreated(msg ApplicationCreatedMessage) error {
+func (p *ApplicationPublisher) PublishApplicationCreated(ctx context.Context, msg ApplicationCreatedMessage) (err error) {
+	defer func() { metrics.Published("application_created", err) }()
+	msg.RequestID = logging.RequestID(ctx)
+	ctx, span := tracing.StartPublish(ctx, "", "application_created")
+	defer func() { tracing.End(span, err) }()
+
+	if p.PublishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.PublishTimeout)
+		defer cancel()
+	}
+
+	// amqp091 не учитывает контекст при публикации, поэтому ждём отправку не дольше ctx
+	done := make(chan error, 1)
+	go func() {
+		done <- p.send(ctx, msg)
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "event published", "component", "publisher",
+		"routing_key", "application_created", "application_id", msg.ID, "owner_id", msg.UserID)
+	return nil
+}
+
+// send объявляет очередь application_created и отправляет в неё сообщение
+func (p *ApplicationPublisher) send(ctx context.Context, msg ApplicationCreatedMessage) error {
 	ch, err := p.MQConn.Channel()
 	if err != nil {
 		return err
@@ -43,13 +84,10 @@ func (p *ApplicationPublisher) PublishApplicationCreated(msg ApplicationCreatedM
 
 	body, _ := json.Marshal(msg)
 
-	if err := ch.Publish("", q.Name, false, false, amqp.Publishing{
-		ContentType: "application/json",
-		Body:        body,
-	}); err != nil {
-		return err
-	}
-
-	log.Printf("[publisher] Published application_created: app_id=%d user_id=%d\n", msg.ID, msg.UserID)
-	return nil
+	return ch.PublishWithContext(ctx, "", q.Name, false, false, amqp.Publishing{
+		ContentType:   "application/json",
+		CorrelationId: logging.RequestID(ctx),
+		Headers:       tracing.InjectAMQP(ctx, nil),
+		Body:          body,
+	})
 }
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"shopflow/application/metrics"
+	"shopflow/application/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository struct {
+	DB *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{DB: db}
+}
+
+const apiKeyColumns = `id, name, prefix, user_id, email, scopes, created_by, created_at,
+	expires_at, last_used_at, revoked_at`
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*models.APIKey, error) {
+	var k models.APIKey
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&k.ID,
+		&k.Name,
+		&k.Prefix,
+		&k.UserID,
+		&k.Email,
+		pq.Array(&k.Scopes),
+		&k.CreatedBy,
+		&k.CreatedAt,
+		&expiresAt,
+		&lastUsedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	return &k, nil
+}
+
+// Create — сохранить ключ; hash — SHA-256 ключа в hex
+func (r *APIKeyRepository) Create(ctx context.Context, k *models.APIKey, hash string) error {
+	defer metrics.ObserveQuery("api_key", "Create")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	var expiresAt time.Time
+	if k.ExpiresAt != nil {
+		expiresAt = k.ExpiresAt.UTC()
+	}
+	return r.DB.QueryRowContext(ctx, `
+		INSERT INTO api_keys (name, prefix, key_hash, user_id, email, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		k.Name, k.Prefix, hash, k.UserID, k.Email, pq.Array(k.Scopes), k.CreatedBy, nullTime(expiresAt),
+	).Scan(&k.ID, &k.CreatedAt)
+}
+
+// List — все ключи, включая отозванные и истёкшие
+func (r *APIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	defer metrics.ObserveQuery("api_key", "List")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+// GetByHash — ключ по хэшу; sql.ErrNoRows, если такого нет
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	defer metrics.ObserveQuery("api_key", "GetByHash")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	return scanAPIKey(r.DB.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, hash))
+}
+
+// Revoke — отозвать ключ; повторный отзыв сохраняет исходное время.
+// sql.ErrNoRows, если ключа нет.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id uint) error {
+	defer metrics.ObserveQuery("api_key", "Revoke")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// TouchLastUsed — отметить использование ключа
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id uint, at time.Time) error {
+	defer metrics.ObserveQuery("api_key", "TouchLastUsed")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, at.UTC())
+	return err
+}
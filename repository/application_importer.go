@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"shopflow/application/metrics"
+	"shopflow/application/models"
+	"shopflow/application/tracing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ApplicationImporter — массовая загрузка заявок через COPY.
+// Строки копируются во временную таблицу, а при Commit переносятся в user_applications
+// одним INSERT ... SELECT, чтобы получить назначенные ID.
+type ApplicationImporter struct {
+	tx   *sql.Tx
+	stmt *sql.Stmt
+	done bool
+}
+
+// BeginImport открывает транзакцию загрузки. Таймаут запросов к ней не применяется:
+// транзакция живёт, пока не отменён ctx (запрос клиента или команда import).
+func (r *ApplicationRepository) BeginImport(ctx context.Context) (_ *ApplicationImporter, err error) {
+	defer metrics.ObserveQuery("application", "BeginImport")()
+	ctx, span := tracing.StartQuery(ctx, "application", "BeginImport")
+	defer func() { tracing.EndQuery(span, err) }()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TEMP TABLE import_staging
+		(
+			user_id    INT,
+			text       TEXT,
+			file_url   VARCHAR(120),
+			status     VARCHAR(20),
+			created_at TIMESTAMP,
+			updated_at TIMESTAMP
+		) ON COMMIT DROP`); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("import_staging", "user_id", "text", "file_url", "status", "created_at", "updated_at"))
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return &ApplicationImporter{tx: tx, stmt: stmt}, nil
+}
+
+// Add добавляет строку в COPY. Пустые Status, CreatedAt и UpdatedAt
+// при переносе заменяются на "new" и NOW().
+func (i *ApplicationImporter) Add(ctx context.Context, app models.Application) error {
+	_, err := i.stmt.ExecContext(ctx, app.UserID, app.Text, app.FileURL, app.Status, nullTime(app.CreatedAt), nullTime(app.UpdatedAt))
+	return err
+}
+
+// Commit завершает COPY, переносит строки в user_applications и фиксирует транзакцию.
+// onInserted вызывается для каждой созданной заявки до фиксации.
+func (i *ApplicationImporter) Commit(ctx context.Context, onInserted func(app *models.Application)) (int, error) {
+	defer metrics.ObserveQuery("application_import", "Commit")()
+
+	if _, err := i.stmt.ExecContext(ctx); err != nil {
+		i.Rollback()
+		return 0, err
+	}
+	if err := i.stmt.Close(); err != nil {
+		i.Rollback()
+		return 0, err
+	}
+
+	rows, err := i.tx.QueryContext(ctx, `
+		INSERT INTO user_applications (user_id, text, file_url, status, created_at, updated_at)
+		SELECT user_id,
+		       text,
+		       NULLIF(file_url, ''),
+		       COALESCE(NULLIF(status, ''), 'new'),
+		       COALESCE(created_at, NOW()),
+		       COALESCE(updated_at, created_at, NOW())
+		FROM import_staging
+		RETURNING id, user_id, text, COALESCE(file_url, ''), status, version, created_at, updated_at`)
+	if err != nil {
+		i.Rollback()
+		return 0, err
+	}
+
+	inserted := 0
+	var app models.Application
+	for rows.Next() {
+		if err := rows.Scan(&app.ID, &app.UserID, &app.Text, &app.FileURL, &app.Status, &app.Version, &app.CreatedAt, &app.UpdatedAt); err != nil {
+			rows.Close()
+			i.Rollback()
+			return 0, err
+		}
+		inserted++
+		if onInserted != nil {
+			onInserted(&app)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		i.Rollback()
+		return 0, err
+	}
+
+	i.done = true
+	if err := i.tx.Commit(); err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+// Rollback отменяет загрузку; после Commit ничего не делает
+func (i *ApplicationImporter) Rollback() {
+	if i.done {
+		return
+	}
+	i.done = true
+	_ = i.stmt.Close()
+	_ = i.tx.Rollback()
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
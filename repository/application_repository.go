@@ -1,53 +1,135 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"shopflow/application/metrics"
 	"shopflow/application/models"
+	"shopflow/application/tracing"
+	"strings"
 )
 
+// ErrVersionConflict — версия заявки в БД не совпадает с ожидаемой (If-Match)
+var ErrVersionConflict = errors.New("application version conflict")
+
+// dbtx — общие методы *sql.DB и *sql.Tx
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type ApplicationRepository struct {
 	DB *sql.DB
+	tx *sql.Tx
 }
 
 func NewApplicationRepository(db *sql.DB) *ApplicationRepository {
 	return &ApplicationRepository{DB: db}
 }
 
+// conn — текущая транзакция, если репозиторий получен через InTx, иначе пул соединений
+func (r *ApplicationRepository) conn() dbtx {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.DB
+}
+
+// InTx выполняет fn в транзакции: репозиторий, переданный в fn, работает внутри неё.
+// Если fn возвращает ошибку, транзакция откатывается.
+func (r *ApplicationRepository) InTx(ctx context.Context, fn func(repo *ApplicationRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(&ApplicationRepository{DB: r.DB, tx: tx}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 // Create — создать новую заявку
-func (r *ApplicationRepository) Create(app *models.Application) error {
+func (r *ApplicationRepository) Create(ctx context.Context, app *models.Application) (err error) {
+	defer metrics.ObserveQuery("application", "Create")()
+	ctx, span := tracing.StartQuery(ctx, "application", "Create")
+	defer func() { tracing.EndQuery(span, err) }()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
 	query := `
 		INSERT INTO user_applications (user_id, text, file_url, status, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, NOW(), NOW())
-		RETURNING id, created_at, updated_at
+		RETURNING id, version, created_at, updated_at
 	`
 
-	return r.DB.QueryRow(
+	return r.conn().QueryRowContext(
+		ctx,
 		query,
 		app.UserID,
 		app.Text,
 		app.FileURL,
 		app.Status,
-	).Scan(&app.ID, &app.CreatedAt, &app.UpdatedAt)
+	).Scan(&app.ID, &app.Version, &app.CreatedAt, &app.UpdatedAt)
 }
 
-// GetAll — получить одну последнюю заявку (если нужен только *Application)
-func (r *ApplicationRepository) GetAll(userID uint) ([]models.Application, error) {
-	query := `
-		SELECT id, user_id, text, file_url, status, created_at, updated_at
-		FROM user_applications
-	`
-	var rows *sql.Rows
-	var err error
+// filterClause строит WHERE-условие по фильтру; плейсхолдеры нумеруются с argOffset+1
+func filterClause(f models.ApplicationFilter, argOffset int) (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, argOffset+len(args)))
+	}
 
-	if userID != 0 {
-		query += " WHERE user_id = $1 ORDER BY created_at DESC"
-		rows, err = r.DB.Query(query, userID)
-	} else {
-		query += " ORDER BY created_at DESC"
-		rows, err = r.DB.Query(query)
+	if f.UserID != 0 {
+		add("user_id = $%d", f.UserID)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if f.ExcludeStatus != "" {
+		add("status <> $%d", f.ExcludeStatus)
+	}
+	if f.AssigneeID != 0 {
+		add("assignee_id = $%d", f.AssigneeID)
+	}
+	if f.CreatedBefore != nil {
+		add("created_at < $%d", *f.CreatedBefore)
+	}
+	if f.CreatedAfter != nil {
+		add("created_at >= $%d", *f.CreatedAfter)
 	}
+	if f.MaxID != 0 {
+		add("id <= $%d", f.MaxID)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// GetAll — получить заявки, подходящие под фильтр, от новых к старым
+func (r *ApplicationRepository) GetAll(ctx context.Context, filter models.ApplicationFilter) (_ []models.Application, err error) {
+	defer metrics.ObserveQuery("application", "GetAll")()
+	ctx, span := tracing.StartQuery(ctx, "application", "GetAll")
+	defer func() { tracing.EndQuery(span, err) }()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	where, args := filterClause(filter, 0)
+	query := `
+		SELECT id, user_id, text, COALESCE(file_url, ''), status, version, created_at, updated_at, assignee_id
+		FROM user_applications` + where + " ORDER BY created_at DESC"
 
+	rows, err := r.conn().QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -56,30 +138,193 @@ func (r *ApplicationRepository) GetAll(userID uint) ([]models.Application, error
 	var apps []models.Application
 	for rows.Next() {
 		var app models.Application
-		if err := rows.Scan(&app.ID, &app.UserID, &app.Text, &app.FileURL, &app.Status, &app.CreatedAt, &app.UpdatedAt); err != nil {
+		if err := rows.Scan(&app.ID, &app.UserID, &app.Text, &app.FileURL, &app.Status, &app.Version, &app.CreatedAt, &app.UpdatedAt, &app.AssigneeID); err != nil {
 			return nil, err
 		}
 		apps = append(apps, app)
 	}
 
-	return apps, nil
+	return apps, rows.Err()
+}
+
+// StreamByFilter — построчно передать в fn заявки, подходящие под фильтр, в порядке ID.
+// Строки читаются из курсора по одной и не накапливаются в памяти; ошибка fn прерывает чтение.
+// Таймаут чтения не применяется: выгрузка ограничена только ctx.
+func (r *ApplicationRepository) StreamByFilter(ctx context.Context, filter models.ApplicationFilter, fn func(app *models.Application) error) error {
+	where, args := filterClause(filter, 0)
+	query := `
+		SELECT id, user_id, text, COALESCE(file_url, ''), status, version, created_at, updated_at, assignee_id
+		FROM user_applications` + where + " ORDER BY id"
+
+	rows, err := r.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var app models.Application
+	for rows.Next() {
+		if err := rows.Scan(&app.ID, &app.UserID, &app.Text, &app.FileURL, &app.Status, &app.Version, &app.CreatedAt, &app.UpdatedAt, &app.AssigneeID); err != nil {
+			return err
+		}
+		if err := fn(&app); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CountByFilter — количество заявок, подходящих под фильтр
+func (r *ApplicationRepository) CountByFilter(ctx context.Context, filter models.ApplicationFilter) (_ int, err error) {
+	defer metrics.ObserveQuery("application", "CountByFilter")()
+	ctx, span := tracing.StartQuery(ctx, "application", "CountByFilter")
+	defer func() { tracing.EndQuery(span, err) }()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	where, args := filterClause(filter, 0)
+	var count int
+	err = r.conn().QueryRowContext(ctx, `SELECT COUNT(*) FROM user_applications`+where, args...).Scan(&count)
+	return count, err
+}
+
+// SampleIDsByFilter — первые limit ID заявок, подходящих под фильтр
+func (r *ApplicationRepository) SampleIDsByFilter(ctx context.Context, filter models.ApplicationFilter, limit int) (_ []uint, err error) {
+	defer metrics.ObserveQuery("application", "SampleIDsByFilter")()
+	ctx, span := tracing.StartQuery(ctx, "application", "SampleIDsByFilter")
+	defer func() { tracing.EndQuery(span, err) }()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	where, args := filterClause(filter, 0)
+	args = append(args, limit)
+	rows, err := r.conn().QueryContext(
+		ctx,
+		fmt.Sprintf(`SELECT id FROM user_applications%s ORDER BY id LIMIT $%d`, where, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []uint{}
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// MaxID — максимальный ID заявки (0, если таблица пуста)
+func (r *ApplicationRepository) MaxID(ctx context.Context) (_ uint, err error) {
+	defer metrics.ObserveQuery("application", "MaxID")()
+	ctx, span := tracing.StartQuery(ctx, "application", "MaxID")
+	defer func() { tracing.EndQuery(span, err) }()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	var id uint
+	err = r.conn().QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM user_applications`).Scan(&id)
+	return id, err
+}
+
+// CountByStatus — количество заявок в каждом статусе
+func (r *ApplicationRepository) CountByStatus(ctx context.Context) (_ map[string]int, err error) {
+	defer metrics.ObserveQuery("application", "CountByStatus")()
+	ctx, span := tracing.StartQuery(ctx, "application", "CountByStatus")
+	defer func() { tracing.EndQuery(span, err) }()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	rows, err := r.conn().QueryContext(ctx, `SELECT status, COUNT(*) FROM user_applications GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var (
+			status string
+			count  int
+		)
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// SetStatusByFilter — сменить статус не более чем limit заявкам, подходящим под фильтр.
+// Заблокированные другими транзакциями строки пропускаются (вызывающий повторяет попытку,
+// см. CountByFilter), чтобы задача не ждала чужие транзакции. Возвращает изменённые заявки.
+func (r *ApplicationRepository) SetStatusByFilter(ctx context.Context, filter models.ApplicationFilter, status string, limit int) (_ []models.Application, err error) {
+	defer metrics.ObserveQuery("application", "SetStatusByFilter")()
+	ctx, span := tracing.StartQuery(ctx, "application", "SetStatusByFilter")
+	defer func() { tracing.EndQuery(span, err) }()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	where, args := filterClause(filter, 2)
+	query := fmt.Sprintf(`
+		UPDATE user_applications a
+		SET status = $1, version = a.version + 1, updated_at = NOW()
+		FROM (
+			SELECT id, status FROM user_applications%s
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) prev
+		WHERE a.id = prev.id
+		RETURNING a.id, a.user_id, a.status, a.version, a.created_at, a.updated_at, a.assignee_id, prev.status`, where)
+
+	rows, err := r.conn().QueryContext(ctx, query, append([]any{status, limit}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var apps []models.Application
+	for rows.Next() {
+		var app models.Application
+		if err := rows.Scan(&app.ID, &app.UserID, &app.Status, &app.Version, &app.CreatedAt, &app.UpdatedAt, &app.AssigneeID, &app.PreviousStatus); err != nil {
+			return nil, err
+		}
+		// назначение массовая смена статуса не трогает
+		app.PreviousAssigneeID = app.AssigneeID
+		apps = append(apps, app)
+	}
+	return apps, rows.Err()
 }
 
-func (r *ApplicationRepository) GetApplicationById(id uint) (*models.Application, error) {
+func (r *ApplicationRepository) GetApplicationById(ctx context.Context, id uint) (_ *models.Application, err error) {
+	defer metrics.ObserveQuery("application", "GetApplicationById")()
+	ctx, span := tracing.StartQuery(ctx, "application", "GetApplicationById")
+	defer func() { tracing.EndQuery(span, err) }()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
 	var app models.Application
 	query := `
-    SELECT id, user_id, text, file_url, status, created_at, updated_at
+    SELECT id, user_id, text, COALESCE(file_url, ''), status, version, created_at, updated_at, assignee_id
     FROM user_applications
     WHERE id = $1`
 
-	err := r.DB.QueryRow(query, id).Scan(
+	err = r.conn().QueryRowContext(ctx, query, id).Scan(
 		&app.ID,
 		&app.UserID,
 		&app.Text,
 		&app.FileURL,
 		&app.Status,
+		&app.Version,
 		&app.CreatedAt,
 		&app.UpdatedAt,
+		&app.AssigneeID,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, err
@@ -89,39 +334,91 @@ func (r *ApplicationRepository) GetApplicationById(id uint) (*models.Application
 	return &app, nil
 }
 
-func (r *ApplicationRepository) DeleteApplicationById(id uint) error {
-	result, err := r.DB.Exec(`DELETE FROM user_applications WHERE id = $1`, id)
-	if err != nil {
-		return err
+// DeleteApplicationById — удалить заявку и вернуть её последнее состояние (владелец,
+// назначенный сотрудник — нужны событию об удалении). Если expectedVersion != 0,
+// удаление выполняется только при совпадении версии, иначе ErrVersionConflict.
+func (r *ApplicationRepository) DeleteApplicationById(ctx context.Context, id uint, expectedVersion int) (_ *models.Application, err error) {
+	defer metrics.ObserveQuery("application", "DeleteApplicationById")()
+	ctx, span := tracing.StartQuery(ctx, "application", "DeleteApplicationById")
+	defer func() { tracing.EndQuery(span, err) }()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	var app models.Application
+	err = r.conn().QueryRowContext(
+		ctx,
+		`DELETE FROM user_applications WHERE id = $1 AND ($2 = 0 OR version = $2)
+        RETURNING id, user_id, status, version, assignee_id`,
+		id, expectedVersion,
+	).Scan(&app.ID, &app.UserID, &app.Status, &app.Version, &app.AssigneeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, r.missingOrConflict(ctx, id, expectedVersion)
 	}
-	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return nil, err
 	}
-
-	return nil
+	return &app, nil
 }
 
-func (r *ApplicationRepository) UpdateApplication(app models.Application, id uint) (*models.Application, error) {
+// UpdateApplication — частично обновить заявку и увеличить её версию: поля patch,
+// равные nil, не изменяются; AssigneeID == 0 снимает назначение. Если expectedVersion != 0, обновление выполняется
+// только при совпадении версии, иначе ErrVersionConflict.
+func (r *ApplicationRepository) UpdateApplication(ctx context.Context, patch models.UpdateApplicationRequest, id uint, expectedVersion int) (_ *models.Application, err error) {
+	defer metrics.ObserveQuery("application", "UpdateApplication")()
+	ctx, span := tracing.StartQuery(ctx, "application", "UpdateApplication")
+	defer func() { tracing.EndQuery(span, err) }()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	// prev — строка до изменения: её статус и назначение нужны подписчикам событий
 	query := `
-        UPDATE user_applications
-        SET text = $1, status = $2, file_url = $3, updated_at = NOW()
-        WHERE id = $4
-        RETURNING id, created_at, updated_at`
-	err := r.DB.QueryRow(query, app.Text, app.Status, app.FileURL, id).Scan(
+        UPDATE user_applications a
+        SET text = COALESCE($1, a.text),
+            status = COALESCE($2, a.status),
+            file_url = COALESCE($3, a.file_url),
+            assignee_id = CASE WHEN $6::int IS NULL THEN a.assignee_id ELSE NULLIF($6, 0) END,
+            version = a.version + 1,
+            updated_at = NOW()
+        FROM (SELECT id, status, assignee_id FROM user_applications WHERE id = $4 FOR UPDATE) prev
+        WHERE a.id = prev.id AND ($5 = 0 OR a.version = $5)
+        RETURNING a.id, a.user_id, a.text, COALESCE(a.file_url, ''), a.status, a.version, a.created_at, a.updated_at, a.assignee_id,
+            prev.status, prev.assignee_id`
+
+	var app models.Application
+	err = r.conn().QueryRowContext(ctx, query, patch.Text, patch.Status, patch.FileURL, id, expectedVersion, patch.AssigneeID).Scan(
 		&app.ID,
+		&app.UserID,
+		&app.Text,
+		&app.FileURL,
+		&app.Status,
+		&app.Version,
 		&app.CreatedAt,
 		&app.UpdatedAt,
+		&app.AssigneeID,
+		&app.PreviousStatus,
+		&app.PreviousAssigneeID,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, err
+		return nil, r.missingOrConflict(ctx, id, expectedVersion)
 	}
 	if err != nil {
 		return nil, err
 	}
 	return &app, nil
 }
+
+// missingOrConflict различает отсутствие заявки и несовпадение версии
+// после того, как условный UPDATE/DELETE не затронул ни одной строки.
+func (r *ApplicationRepository) missingOrConflict(ctx context.Context, id uint, expectedVersion int) error {
+	if expectedVersion == 0 {
+		return sql.ErrNoRows
+	}
+	var exists bool
+	if err := r.conn().QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM user_applications WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return ErrVersionConflict
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"shopflow/application/metrics"
+	"shopflow/application/models"
+	"time"
+)
+
+// EventsChannel — канал Postgres NOTIFY, в который публикуется каждое новое событие
+const EventsChannel = "application_events"
+
+type EventRepository struct {
+	DB *sql.DB
+}
+
+func NewEventRepository(db *sql.DB) *EventRepository {
+	return &EventRepository{DB: db}
+}
+
+// maxNotifyPayload — предел полезной нагрузки NOTIFY (8000 байт) с запасом
+const maxNotifyPayload = 7500
+
+// Append — записать событие в журнал и оповестить все реплики через NOTIFY.
+// Уведомление содержит саму запись в JSON, поэтому слушателям не нужно перечитывать
+// её из таблицы; слишком большие события отправляются без data.
+func (r *EventRepository) Append(ctx context.Context, eventType string, userID uint, payload []byte) (*models.ApplicationEvent, error) {
+	defer metrics.ObserveQuery("event", "Append")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	e := models.ApplicationEvent{Type: eventType, UserID: userID, Data: payload}
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO application_events (event_type, user_id, payload)
+        VALUES ($1, NULLIF($2, 0), $3)
+        RETURNING id, created_at`,
+		eventType, userID, string(payload),
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	notification, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	if len(notification) > maxNotifyPayload {
+		short := e
+		short.Data = nil
+		if notification, err = json.Marshal(short); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, EventsChannel, string(notification)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// GetByID — событие по ID
+func (r *EventRepository) GetByID(ctx context.Context, id int64) (*models.ApplicationEvent, error) {
+	defer metrics.ObserveQuery("event", "GetByID")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	var e models.ApplicationEvent
+	var data []byte
+	err := r.DB.QueryRowContext(ctx, `
+        SELECT id, event_type, COALESCE(user_id, 0), payload, created_at
+        FROM application_events
+        WHERE id = $1`,
+		id,
+	).Scan(&e.ID, &e.Type, &e.UserID, &data, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	e.Data = data
+	return &e, nil
+}
+
+// ListSince — события с ID больше afterID по возрастанию. userID == 0 — события всех пользователей.
+func (r *EventRepository) ListSince(ctx context.Context, afterID int64, userID uint, limit int) ([]models.ApplicationEvent, error) {
+	defer metrics.ObserveQuery("event", "ListSince")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT id, event_type, COALESCE(user_id, 0), payload, created_at
+        FROM application_events
+        WHERE id > $1 AND ($2 = 0 OR user_id = $2)
+        ORDER BY id
+        LIMIT $3`,
+		afterID, userID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.ApplicationEvent
+	for rows.Next() {
+		var e models.ApplicationEvent
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.Type, &e.UserID, &data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Data = data
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// OldestID — ID самого старого события в журнале (0, если журнал пуст)
+func (r *EventRepository) OldestID(ctx context.Context) (int64, error) {
+	defer metrics.ObserveQuery("event", "OldestID")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	var id int64
+	err := r.DB.QueryRowContext(ctx, `SELECT COALESCE(MIN(id), 0) FROM application_events`).Scan(&id)
+	return id, err
+}
+
+// PurgeOlderThan — удалить события старше retention
+func (r *EventRepository) PurgeOlderThan(ctx context.Context, retention time.Duration) (int64, error) {
+	defer metrics.ObserveQuery("event", "PurgeOlderThan")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(
+		ctx,
+		`DELETE FROM application_events WHERE created_at < $1`,
+		time.Now().Add(-retention),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"shopflow/application/metrics"
+	"shopflow/application/models"
+	"time"
+)
+
+type IdempotencyRepository struct {
+	DB *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{DB: db}
+}
+
+// Reserve — занять ключ за пользователем. Возвращает true, если ключ свободен и
+// теперь принадлежит текущему запросу; false — если ключ уже существует
+// (тогда запись можно прочитать через Get). Просроченные ключи освобождаются.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, userID uint, key, requestHash string, ttl time.Duration) (bool, error) {
+	defer metrics.ObserveQuery("idempotency", "Reserve")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	if _, err := r.DB.ExecContext(
+		ctx,
+		`DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND expires_at < NOW()`,
+		userID, key,
+	); err != nil {
+		return false, err
+	}
+
+	result, err := r.DB.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, request_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, NOW(), $4)
+		ON CONFLICT (user_id, key) DO NOTHING`,
+		userID, key, requestHash, time.Now().Add(ttl),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Get — получить сохранённую запись ключа
+func (r *IdempotencyRepository) Get(ctx context.Context, userID uint, key string) (*models.IdempotencyRecord, error) {
+	defer metrics.ObserveQuery("idempotency", "Get")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	var rec models.IdempotencyRecord
+	var status sql.NullInt64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT user_id, key, request_hash, status_code, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2`,
+		userID, key,
+	).Scan(&rec.UserID, &rec.Key, &rec.RequestHash, &status, &rec.ResponseBody, &rec.CreatedAt, &rec.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	rec.StatusCode = int(status.Int64)
+	return &rec, nil
+}
+
+// Complete — сохранить ответ для повторной выдачи при ретраях
+func (r *IdempotencyRepository) Complete(ctx context.Context, userID uint, key string, statusCode int, body []byte) error {
+	defer metrics.ObserveQuery("idempotency", "Complete")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $3, response_body = $4
+		WHERE user_id = $1 AND key = $2`,
+		userID, key, statusCode, body,
+	)
+	return err
+}
+
+// Release — освободить ключ, если запрос завершился неуспешно и его можно повторить
+func (r *IdempotencyRepository) Release(ctx context.Context, userID uint, key string) error {
+	defer metrics.ObserveQuery("idempotency", "Release")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(
+		ctx,
+		`DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL`,
+		userID, key,
+	)
+	return err
+}
+
+// PurgeExpired — удалить все просроченные ключи
+func (r *IdempotencyRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	defer metrics.ObserveQuery("idempotency", "PurgeExpired")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"shopflow/application/metrics"
+	"shopflow/application/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type JobRepository struct {
+	DB *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{DB: db}
+}
+
+const jobColumns = `id, type, status, params, result, total, processed, COALESCE(error, ''),
+	cancel_requested, created_by, created_at, updated_at, finished_at`
+
+func scanJob(row interface{ Scan(...any) error }) (*models.Job, error) {
+	var job models.Job
+	var params, result []byte
+	var finishedAt sql.NullTime
+	err := row.Scan(
+		&job.ID,
+		&job.Type,
+		&job.Status,
+		&params,
+		&result,
+		&job.Total,
+		&job.Processed,
+		&job.Error,
+		&job.CancelRequested,
+		&job.CreatedBy,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&finishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	job.Params = json.RawMessage(params)
+	if result != nil {
+		job.Result = json.RawMessage(result)
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// Create — создать задачу в статусе pending
+func (r *JobRepository) Create(ctx context.Context, job *models.Job) error {
+	defer metrics.ObserveQuery("job", "Create")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO jobs (type, status, params, total, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING ` + jobColumns
+
+	created, err := scanJob(r.DB.QueryRowContext(ctx, query, job.Type, models.JobStatusPending, string(job.Params), job.Total, job.CreatedBy))
+	if err != nil {
+		return err
+	}
+	*job = *created
+	return nil
+}
+
+// GetByID — получить задачу по ID
+func (r *JobRepository) GetByID(ctx context.Context, id uint) (*models.Job, error) {
+	defer metrics.ObserveQuery("job", "GetByID")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	job, err := scanJob(r.DB.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// MarkRunning — перевести задачу в running с известным общим объёмом работы
+func (r *JobRepository) MarkRunning(ctx context.Context, id uint, total int) error {
+	defer metrics.ObserveQuery("job", "MarkRunning")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE jobs SET status = $2, total = $3, updated_at = NOW()
+		WHERE id = $1`,
+		id, models.JobStatusRunning, total,
+	)
+	return err
+}
+
+// UpdateProgress — сохранить прогресс и вернуть, запрошена ли отмена задачи
+func (r *JobRepository) UpdateProgress(ctx context.Context, id uint, processed int) (cancelRequested bool, err error) {
+	defer metrics.ObserveQuery("job", "UpdateProgress")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	err = r.DB.QueryRowContext(ctx, `
+		UPDATE jobs SET processed = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING cancel_requested`,
+		id, processed,
+	).Scan(&cancelRequested)
+	return cancelRequested, err
+}
+
+// Finish — завершить задачу с итоговым статусом, результатом и (необязательно) ошибкой
+func (r *JobRepository) Finish(ctx context.Context, id uint, status string, result any, jobErr error) error {
+	defer metrics.ObserveQuery("job", "Finish")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	// JSONB передаём строкой: lib/pq кодирует []byte как bytea
+	var resultJSON sql.NullString
+	if result != nil {
+		b, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resultJSON = sql.NullString{String: string(b), Valid: true}
+	}
+	var errText sql.NullString
+	if jobErr != nil {
+		errText = sql.NullString{String: jobErr.Error(), Valid: true}
+	}
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE jobs SET status = $2, result = $3, error = $4, updated_at = NOW(), finished_at = NOW()
+		WHERE id = $1`,
+		id, status, resultJSON, errText,
+	)
+	return err
+}
+
+// Touch — отметить, что задачи ещё выполняются (heartbeat реплики)
+func (r *JobRepository) Touch(ctx context.Context, ids []uint) error {
+	defer metrics.ObserveQuery("job", "Touch")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	if len(ids) == 0 {
+		return nil
+	}
+	ids64 := make([]int64, len(ids))
+	for i, id := range ids {
+		ids64[i] = int64(id)
+	}
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE jobs SET updated_at = NOW()
+		WHERE id = ANY($1) AND status IN ($2, $3)`,
+		pq.Array(ids64), models.JobStatusPending, models.JobStatusRunning,
+	)
+	return err
+}
+
+// FailStale — завершить со статусом failed незавершённые задачи, которые не обновлялись
+// дольше staleAfter: реплика, выполнявшая их, упала. Возвращает число таких задач.
+func (r *JobRepository) FailStale(ctx context.Context, staleAfter time.Duration) (int64, error) {
+	defer metrics.ObserveQuery("job", "FailStale")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx, `
+		UPDATE jobs SET status = $1, error = $2, updated_at = NOW(), finished_at = NOW()
+		WHERE status IN ($3, $4) AND updated_at < NOW() - $5 * INTERVAL '1 second'`,
+		models.JobStatusFailed, "job was interrupted: the replica running it stopped",
+		models.JobStatusPending, models.JobStatusRunning, staleAfter.Seconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeFinishedExports — удалить выгрузки, завершившиеся раньше retention назад, вместе
+// с их файлами (export_chunks удаляются каскадом). Возвращает число удалённых задач.
+func (r *JobRepository) PurgeFinishedExports(ctx context.Context, retention time.Duration) (int64, error) {
+	defer metrics.ObserveQuery("job", "PurgeFinishedExports")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx, `
+		DELETE FROM jobs
+		WHERE type = $1 AND status IN ($2, $3, $4) AND finished_at < $5`,
+		models.JobTypeExport, models.JobStatusSucceeded, models.JobStatusFailed, models.JobStatusCancelled,
+		time.Now().Add(-retention).UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SaveExportChunk — сохранить часть seq файла выгрузки задачи
+func (r *JobRepository) SaveExportChunk(ctx context.Context, jobID uint, seq int, data []byte) error {
+	defer metrics.ObserveQuery("job", "SaveExportChunk")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx,
+		`INSERT INTO export_chunks (job_id, seq, data) VALUES ($1, $2, $3)`,
+		jobID, seq, data,
+	)
+	return err
+}
+
+// ExportChunk — часть seq файла выгрузки; sql.ErrNoRows, если такой нет
+func (r *JobRepository) ExportChunk(ctx context.Context, jobID uint, seq int) ([]byte, error) {
+	defer metrics.ObserveQuery("job", "ExportChunk")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	var data []byte
+	err := r.DB.QueryRowContext(ctx,
+		`SELECT data FROM export_chunks WHERE job_id = $1 AND seq = $2`,
+		jobID, seq,
+	).Scan(&data)
+	return data, err
+}
+
+// DeleteExportChunks — удалить файл выгрузки задачи (недописанный после ошибки)
+func (r *JobRepository) DeleteExportChunks(ctx context.Context, jobID uint) error {
+	defer metrics.ObserveQuery("job", "DeleteExportChunks")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM export_chunks WHERE job_id = $1`, jobID)
+	return err
+}
+
+// RequestCancel — пометить задачу на отмену. Возвращает sql.ErrNoRows,
+// если задачи нет или она уже завершена.
+func (r *JobRepository) RequestCancel(ctx context.Context, id uint) (*models.Job, error) {
+	defer metrics.ObserveQuery("job", "RequestCancel")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	job, err := scanJob(r.DB.QueryRowContext(ctx, `
+		UPDATE jobs SET cancel_requested = TRUE, updated_at = NOW()
+		WHERE id = $1 AND status IN ($2, $3)
+		RETURNING `+jobColumns,
+		id, models.JobStatusPending, models.JobStatusRunning,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
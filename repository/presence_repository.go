@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"shopflow/application/metrics"
+	"shopflow/application/models"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PresenceChannel — канал Postgres NOTIFY с ID заявки, у которой изменился состав зрителей
+const PresenceChannel = "dashboard_presence"
+
+// PresenceRepository хранит, кто из сотрудников сейчас смотрит заявку.
+// Записи живых сессий периодически продлеваются, записи упавших реплик устаревают.
+type PresenceRepository struct {
+	DB *sql.DB
+}
+
+func NewPresenceRepository(db *sql.DB) *PresenceRepository {
+	return &PresenceRepository{DB: db}
+}
+
+func (r *PresenceRepository) notify(ctx context.Context, tx *sql.Tx, appID uint) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, PresenceChannel, strconv.FormatUint(uint64(appID), 10))
+	return err
+}
+
+// Join — сессия открыла заявку
+func (r *PresenceRepository) Join(ctx context.Context, appID uint, sessionID string, userID uint, email string) error {
+	defer metrics.ObserveQuery("presence", "Join")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO dashboard_presence (application_id, session_id, user_id, email)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (application_id, session_id) DO UPDATE SET seen_at = NOW()`,
+		appID, sessionID, userID, email,
+	)
+	if err != nil {
+		return err
+	}
+	if err := r.notify(ctx, tx, appID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Leave — сессия закрыла заявку
+func (r *PresenceRepository) Leave(ctx context.Context, appID uint, sessionID string) error {
+	defer metrics.ObserveQuery("presence", "Leave")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM dashboard_presence WHERE application_id = $1 AND session_id = $2`,
+		appID, sessionID,
+	); err != nil {
+		return err
+	}
+	if err := r.notify(ctx, tx, appID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Touch — продлить записи живых сессий
+func (r *PresenceRepository) Touch(ctx context.Context, sessionIDs []string) error {
+	defer metrics.ObserveQuery("presence", "Touch")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	_, err := r.DB.ExecContext(
+		ctx,
+		`UPDATE dashboard_presence SET seen_at = NOW() WHERE session_id = ANY($1)`,
+		pq.Array(sessionIDs),
+	)
+	return err
+}
+
+// PurgeStale — удалить записи, не продлевавшиеся дольше ttl, и оповестить о затронутых заявках
+func (r *PresenceRepository) PurgeStale(ctx context.Context, ttl time.Duration) error {
+	defer metrics.ObserveQuery("presence", "PurgeStale")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`DELETE FROM dashboard_presence WHERE seen_at < $1 RETURNING application_id`,
+		time.Now().Add(-ttl),
+	)
+	if err != nil {
+		return err
+	}
+	affected := map[uint]bool{}
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		affected[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id := range affected {
+		if err := r.notify(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Viewers — кто сейчас смотрит заявку (по одному элементу на сотрудника)
+func (r *PresenceRepository) Viewers(ctx context.Context, appID uint) ([]models.PresenceViewer, error) {
+	defer metrics.ObserveQuery("presence", "Viewers")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT user_id, MAX(email), MIN(since)
+        FROM dashboard_presence
+        WHERE application_id = $1
+        GROUP BY user_id
+        ORDER BY MIN(since)`,
+		appID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	viewers := []models.PresenceViewer{}
+	for rows.Next() {
+		var v models.PresenceViewer
+		if err := rows.Scan(&v.UserID, &v.Email, &v.Since); err != nil {
+			return nil, err
+		}
+		viewers = append(viewers, v)
+	}
+	return viewers, rows.Err()
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"shopflow/application/metrics"
+	"shopflow/application/models"
+	"time"
+)
+
+// RevocationRepository хранит отзывы токенов, чтобы они переживали перезапуск реплик
+type RevocationRepository struct {
+	DB *sql.DB
+}
+
+func NewRevocationRepository(db *sql.DB) *RevocationRepository {
+	return &RevocationRepository{DB: db}
+}
+
+// RevokeToken — отозвать токен по jti (повторный отзыв ничего не меняет)
+func (r *RevocationRepository) RevokeToken(ctx context.Context, t models.RevokedToken) error {
+	defer metrics.ObserveQuery("revocation", "RevokeToken")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, `
+        INSERT INTO revoked_tokens (jti, user_id, expires_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (jti) DO UPDATE SET expires_at = GREATEST(revoked_tokens.expires_at, EXCLUDED.expires_at)`,
+		t.JTI, t.UserID, t.ExpiresAt.UTC(),
+	)
+	return err
+}
+
+// RevokeUserTokens — отозвать токены пользователя, выданные раньше c.IssuedBefore.
+// Отсечка только сдвигается вперёд: запоздавшее событие не вернёт силу отозванным токенам.
+func (r *RevocationRepository) RevokeUserTokens(ctx context.Context, c models.UserTokenCutoff) error {
+	defer metrics.ObserveQuery("revocation", "RevokeUserTokens")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, `
+        INSERT INTO user_token_cutoffs (user_id, issued_before)
+        VALUES ($1, $2)
+        ON CONFLICT (user_id) DO UPDATE SET issued_before = GREATEST(user_token_cutoffs.issued_before, EXCLUDED.issued_before)`,
+		c.UserID, c.IssuedBefore.UTC(),
+	)
+	return err
+}
+
+// Active — действующие отзывы: неистёкшие jti и отсечки моложе retention
+func (r *RevocationRepository) Active(ctx context.Context, retention time.Duration) ([]models.RevokedToken, []models.UserTokenCutoff, error) {
+	defer metrics.ObserveQuery("revocation", "Active")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+	rows, err := r.DB.QueryContext(ctx, `SELECT jti, user_id, expires_at FROM revoked_tokens WHERE expires_at > $1`, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.RevokedToken
+	for rows.Next() {
+		var t models.RevokedToken
+		if err := rows.Scan(&t.JTI, &t.UserID, &t.ExpiresAt); err != nil {
+			return nil, nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
+	rows, err = r.DB.QueryContext(ctx,
+		`SELECT user_id, issued_before FROM user_token_cutoffs WHERE issued_before > $1`,
+		now.Add(-retention),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var cutoffs []models.UserTokenCutoff
+	for rows.Next() {
+		var c models.UserTokenCutoff
+		if err := rows.Scan(&c.UserID, &c.IssuedBefore); err != nil {
+			return nil, nil, err
+		}
+		cutoffs = append(cutoffs, c)
+	}
+	return tokens, cutoffs, rows.Err()
+}
+
+// PurgeExpired — удалить истёкшие jti и отсечки старше retention: выданные до них токены уже истекли
+func (r *RevocationRepository) PurgeExpired(ctx context.Context, retention time.Duration) error {
+	defer metrics.ObserveQuery("revocation", "PurgeExpired")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < $1`, now); err != nil {
+		return err
+	}
+	_, err := r.DB.ExecContext(ctx,
+		`DELETE FROM user_token_cutoffs WHERE issued_before < $1`,
+		now.Add(-retention),
+	)
+	return err
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// QueryTimeouts — предел длительности одного метода репозитория по типу операции.
+// Отсчитывается поверх контекста вызывающего: что наступит раньше — отмена запроса
+// клиентом или таймаут. 0 — без собственного ограничения.
+type QueryTimeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+var queryTimeouts atomic.Pointer[QueryTimeouts]
+
+// SetQueryTimeouts задаёт таймауты запросов всех репозиториев (из конфигурации при запуске)
+func SetQueryTimeouts(t QueryTimeouts) {
+	queryTimeouts.Store(&t)
+}
+
+// readContext ограничивает ctx таймаутом чтения
+func readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t := queryTimeouts.Load(); t != nil && t.Read > 0 {
+		return context.WithTimeout(ctx, t.Read)
+	}
+	return context.WithCancel(ctx)
+}
+
+// writeContext ограничивает ctx таймаутом записи
+func writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t := queryTimeouts.Load(); t != nil && t.Write > 0 {
+		return context.WithTimeout(ctx, t.Write)
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,446 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"shopflow/application/metrics"
+	"shopflow/application/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	DB *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{DB: db}
+}
+
+// ClaimedDelivery — доставка, взятая воркером в работу, вместе с адресом и секретом подписки
+type ClaimedDelivery struct {
+	Delivery models.WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+const subscriptionColumns = `id, url, event_types, active, consecutive_failures,
+	COALESCE(disabled_reason, ''), created_by, created_at, updated_at`
+
+func scanSubscription(row interface{ Scan(...any) error }) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	err := row.Scan(
+		&sub.ID,
+		&sub.URL,
+		pq.Array(&sub.EventTypes),
+		&sub.Active,
+		&sub.ConsecutiveFailures,
+		&sub.DisabledReason,
+		&sub.CreatedBy,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+const deliveryColumns = `id, subscription_id, event_type, payload, status, attempts, next_attempt_at,
+	COALESCE(last_status_code, 0), COALESCE(last_error, ''), COALESCE(last_response, ''), created_at, delivered_at`
+
+func scanDelivery(row interface{ Scan(...any) error }, extra ...any) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	var deliveredAt sql.NullTime
+	dest := append([]any{
+		&d.ID,
+		&d.SubscriptionID,
+		&d.EventType,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastStatusCode,
+		&d.LastError,
+		&d.LastResponse,
+		&d.CreatedAt,
+		&deliveredAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return &d, nil
+}
+
+// CreateSubscription — создать подписку
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	defer metrics.ObserveQuery("webhook", "CreateSubscription")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	return r.DB.QueryRowContext(ctx, `
+		INSERT INTO webhook_subscriptions (url, event_types, secret, active, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, TRUE, $4, NOW(), NOW())
+		RETURNING id, active, created_at, updated_at`,
+		sub.URL, pq.Array(sub.EventTypes), sub.Secret, sub.CreatedBy,
+	).Scan(&sub.ID, &sub.Active, &sub.CreatedAt, &sub.UpdatedAt)
+}
+
+// ListSubscriptions — все подписки
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	defer metrics.ObserveQuery("webhook", "ListSubscriptions")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// GetSubscription — подписка по ID (без секрета)
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	defer metrics.ObserveQuery("webhook", "GetSubscription")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	sub, err := scanSubscription(r.DB.QueryRowContext(ctx, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// UpdateSubscription — частично обновить подписку. Включение (active = true)
+// сбрасывает счётчик ошибок и причину отключения; при выключении (active = false)
+// ожидающие доставки завершаются ошибкой, как при автоматическом отключении.
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, id uint, req models.UpdateWebhookRequest) (*models.WebhookSubscription, error) {
+	defer metrics.ObserveQuery("webhook", "UpdateSubscription")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var eventTypes any
+	if req.EventTypes != nil {
+		eventTypes = pq.Array(req.EventTypes)
+	}
+	sub, err := scanSubscription(tx.QueryRowContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET url = COALESCE($2, url),
+		    event_types = COALESCE($3, event_types),
+		    secret = COALESCE($4, secret),
+		    active = COALESCE($5, active),
+		    consecutive_failures = CASE WHEN $5 THEN 0 ELSE consecutive_failures END,
+		    disabled_reason = CASE WHEN $5 THEN NULL ELSE disabled_reason END,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+subscriptionColumns,
+		id, req.URL, eventTypes, req.Secret, req.Active,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	if req.Active != nil && !*req.Active {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries SET status = $2, last_error = 'subscription disabled'
+			WHERE subscription_id = $1 AND status = $3`,
+			id, models.WebhookDeliveryFailed, models.WebhookDeliveryPending,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return sub, tx.Commit()
+}
+
+// DeleteSubscription — удалить подписку вместе с журналом доставок
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id uint) error {
+	defer metrics.ObserveQuery("webhook", "DeleteSubscription")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// EnqueueEvent — поставить событие в очередь доставки всем активным подпискам на его тип.
+// Возвращает число созданных доставок.
+func (r *WebhookRepository) EnqueueEvent(ctx context.Context, eventType string, payload []byte) (int64, error) {
+	defer metrics.ObserveQuery("webhook", "EnqueueEvent")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_type, payload, status, next_attempt_at, created_at)
+		SELECT id, $1, $2, $3, NOW(), NOW()
+		FROM webhook_subscriptions
+		WHERE active AND ($1 = ANY(event_types) OR '*' = ANY(event_types))`,
+		eventType, string(payload), models.WebhookDeliveryPending,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CreateDelivery — создать доставку конкретной подписке (тестовое событие)
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, subscriptionID uint, eventType string, payload []byte) (*models.WebhookDelivery, error) {
+	defer metrics.ObserveQuery("webhook", "CreateDelivery")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	return scanDelivery(r.DB.QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_type, payload, status, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		RETURNING `+deliveryColumns,
+		subscriptionID, eventType, string(payload), models.WebhookDeliveryPending,
+	))
+}
+
+// ClaimDueDeliveries — взять в работу до limit доставок, время которых пришло.
+// Доставка «арендуется» на lease: если воркер упадёт, её подхватит другой.
+// Строки, заблокированные другими репликами, и доставки отключённых подписок пропускаются.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]ClaimedDelivery, error) {
+	defer metrics.ObserveQuery("webhook", "ClaimDueDeliveries")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	rows, err := r.DB.QueryContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND s.active
+		  AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $3 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		  )
+		RETURNING d.id, d.subscription_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at,
+		          COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''), COALESCE(d.last_response, ''),
+		          d.created_at, d.delivered_at, s.url, s.secret`,
+		limit, int(lease.Seconds()), models.WebhookDeliveryPending,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var claimed []ClaimedDelivery
+	for rows.Next() {
+		var c ClaimedDelivery
+		d, err := scanDelivery(rows, &c.URL, &c.Secret)
+		if err != nil {
+			return nil, err
+		}
+		c.Delivery = *d
+		claimed = append(claimed, c)
+	}
+	return claimed, rows.Err()
+}
+
+// ClaimDelivery — взять в работу конкретную доставку (для немедленной отправки тестового события)
+func (r *WebhookRepository) ClaimDelivery(ctx context.Context, id uint, lease time.Duration) (*ClaimedDelivery, error) {
+	defer metrics.ObserveQuery("webhook", "ClaimDelivery")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	var c ClaimedDelivery
+	d, err := scanDelivery(r.DB.QueryRowContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id = $1
+		RETURNING d.id, d.subscription_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at,
+		          COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''), COALESCE(d.last_response, ''),
+		          d.created_at, d.delivered_at, s.url, s.secret`,
+		id, int(lease.Seconds()),
+	), &c.URL, &c.Secret)
+	if err != nil {
+		return nil, err
+	}
+	c.Delivery = *d
+	return &c, nil
+}
+
+// MarkDelivered — доставка успешна; счётчик ошибок подписки сбрасывается
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, d *models.WebhookDelivery, statusCode int, response string) error {
+	defer metrics.ObserveQuery("webhook", "MarkDelivered")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, last_status_code = $3, last_error = NULL, last_response = $4, delivered_at = NOW()
+		WHERE id = $1`,
+		d.ID, models.WebhookDeliverySucceeded, statusCode, response,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = $1`, d.SubscriptionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkAttemptFailed — попытка доставки не удалась. nextAttempt == nil означает, что попытки
+// исчерпаны. Подписка отключается, когда число ошибок подряд достигает disableAfter
+// (0 — не отключать). Возвращает true, если подписка была отключена этой ошибкой.
+func (r *WebhookRepository) MarkAttemptFailed(ctx context.Context, d *models.WebhookDelivery, statusCode int, errText, response string, nextAttempt *time.Time, disableAfter int) (bool, error) {
+	defer metrics.ObserveQuery("webhook", "MarkAttemptFailed")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	status := models.WebhookDeliveryPending
+	next := time.Now()
+	if nextAttempt == nil {
+		status = models.WebhookDeliveryFailed
+	} else {
+		next = *nextAttempt
+	}
+	var code sql.NullInt64
+	if statusCode != 0 {
+		code = sql.NullInt64{Int64: int64(statusCode), Valid: true}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, next_attempt_at = $3, last_status_code = $4, last_error = $5, last_response = $6
+		WHERE id = $1`,
+		d.ID, status, next, code, errText, response,
+	); err != nil {
+		return false, err
+	}
+
+	var disabled bool
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET consecutive_failures = consecutive_failures + 1,
+		    active = CASE WHEN $2 > 0 AND consecutive_failures + 1 >= $2 THEN FALSE ELSE active END,
+		    disabled_reason = CASE WHEN $2 > 0 AND consecutive_failures + 1 >= $2 AND active
+		                           THEN 'disabled after ' || $2 || ' consecutive delivery failures'
+		                           ELSE disabled_reason END,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING $2 > 0 AND consecutive_failures = $2`,
+		d.SubscriptionID, disableAfter,
+	).Scan(&disabled); err != nil {
+		return false, err
+	}
+
+	if disabled {
+		// Отключённая подписка больше ничего не получает
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries SET status = $2, last_error = 'subscription disabled'
+			WHERE subscription_id = $1 AND status = $3 AND id <> $4`,
+			d.SubscriptionID, models.WebhookDeliveryFailed, models.WebhookDeliveryPending, d.ID,
+		); err != nil {
+			return false, err
+		}
+	}
+	return disabled, tx.Commit()
+}
+
+// PurgeDeliveries — удалить завершённые (успешные и неудачные) доставки старше retention
+func (r *WebhookRepository) PurgeDeliveries(ctx context.Context, retention time.Duration) (int64, error) {
+	defer metrics.ObserveQuery("webhook", "PurgeDeliveries")()
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx,
+		`DELETE FROM webhook_deliveries WHERE status IN ($1, $2) AND created_at < $3`,
+		models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed, time.Now().Add(-retention),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetDelivery — доставка по ID
+func (r *WebhookRepository) GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	defer metrics.ObserveQuery("webhook", "GetDelivery")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	return scanDelivery(r.DB.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id))
+}
+
+// ListDeliveries — последние доставки подписки (журнал)
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	defer metrics.ObserveQuery("webhook", "ListDeliveries")()
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`,
+		subscriptionID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
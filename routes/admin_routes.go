@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"shopflow/application/handlers"
+	"shopflow/application/middleware"
+	"shopflow/application/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAdminRoutes регистрирует административные маршруты (массовые операции, загрузка, вебхуки,
+// API-ключи, уровень логов)
+func RegisterAdminRoutes(r *gin.Engine, appSvc *services.ApplicationService, jobSvc *services.JobService, webhookSvc *services.WebhookService, apiKeySvc *services.APIKeyService) {
+	admin := r.Group("/api/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequireRole(middleware.RoleAdmin))
+
+	h := &handlers.AdminHandler{AppSvc: appSvc, Jobs: jobSvc}
+
+	admin.POST("/applications/bulk-status", h.BulkChangeStatus) // массовая смена статуса по фильтру
+	admin.POST("/applications/import", h.ImportApplications)    // загрузка заявок из CSV/NDJSON
+
+	admin.GET("/log-level", h.GetLogLevel)
+	admin.PUT("/log-level", h.SetLogLevel) // без перезапуска, только для этой реплики
+
+	wh := &handlers.WebhookHandler{Webhooks: webhookSvc}
+
+	admin.POST("/webhooks", wh.CreateWebhook)
+	admin.GET("/webhooks", wh.ListWebhooks)
+	admin.GET("/webhooks/:id", wh.GetWebhook)
+	admin.PATCH("/webhooks/:id", wh.UpdateWebhook)
+	admin.DELETE("/webhooks/:id", wh.DeleteWebhook)
+	admin.GET("/webhooks/:id/deliveries", wh.ListWebhookDeliveries) // журнал доставок
+	admin.POST("/webhooks/:id/test", wh.SendTestWebhook)            // отправка тестового события
+
+	kh := &handlers.APIKeyHandler{Keys: apiKeySvc}
+
+	admin.POST("/api-keys", kh.CreateAPIKey) // ключ возвращается только в ответе
+	admin.GET("/api-keys", kh.ListAPIKeys)
+	admin.DELETE("/api-keys/:id", kh.RevokeAPIKey)
+}
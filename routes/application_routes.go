@@ -3,31 +3,75 @@ package routes
 import (
 	"shopflow/application/handlers"
 	"shopflow/application/middleware"
+	"shopflow/application/models"
+	"shopflow/application/repository"
 	"shopflow/application/services"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Options — настройки HTTP-слоя Application сервиса
+type Options struct {
+	// RequireIfMatch — требовать If-Match на PATCH/DELETE (иначе 428)
+	RequireIfMatch bool
+
+	// Idempotency — хранилище ключей Idempotency-Key для POST /api/applications (nil — отключено)
+	Idempotency *repository.IdempotencyRepository
+	// IdempotencyTTL — сколько хранить ответ для повторной выдачи
+	IdempotencyTTL time.Duration
+
+	// BatchMaxOperations — лимит операций в POST /api/applications:batch
+	BatchMaxOperations int
+
+	// Jobs — сервис фоновых задач (асинхронная выгрузка)
+	Jobs *services.JobService
+
+	// Events — поток событий для SSE (nil — /api/applications/stream отключён)
+	Events *services.EventStream
+	// SSEHeartbeat — интервал пингов в SSE-потоке
+	SSEHeartbeat time.Duration
+
+	// Shutdown закрывается при остановке сервиса и завершает долгоживущие потоки
+	Shutdown <-chan struct{}
+}
+
 // RegisterApplicationRoutes регистрирует маршруты для Application сервиса
-func RegisterApplicationRoutes(r *gin.Engine, appSvc *services.ApplicationService, publisher *services.NotificationService) {
+func RegisterApplicationRoutes(r *gin.Engine, appSvc *services.ApplicationService, publisher *services.NotificationService, opts Options) {
 	api := r.Group("/api")
+	applicationScope := middleware.RequireScope(models.APIKeyScopeApplicationsRead, models.APIKeyScopeApplicationsWrite)
 	{
 		appGroup := api.Group("/applications")
-		appGroup.Use(middleware.AuthMiddleware()) // JWT или любая другая авторизация
+		appGroup.Use(middleware.AuthMiddleware(), applicationScope) // JWT или API-ключ сервиса
 
 		// создаём один экземпляр хендлера с DI
 		h := &handlers.ApplicationHandler{
 			BaseHandler: &handlers.BaseHandler{
-				AppSvc:    appSvc,
-				Publisher: publisher,
+				AppSvc:             appSvc,
+				Publisher:          publisher,
+				Jobs:               opts.Jobs,
+				RequireIfMatch:     opts.RequireIfMatch,
+				BatchMaxOperations: opts.BatchMaxOperations,
 			},
 		}
 
+		idempotent := middleware.IdempotencyMiddleware(opts.Idempotency, opts.IdempotencyTTL)
+
 		// маршруты
-		appGroup.POST("", h.CreateApplication)       // создание заявки (с gRPC Auth проверкой)
-		appGroup.GET("", h.GetApplications)          // получить все заявки текущего пользователя
-		appGroup.GET("/:id", h.GetApplicationById)   // получить заявку по ID
-		appGroup.DELETE("/:id", h.DeleteApplication) // удалить заявку
-		appGroup.PATCH("/:id", h.UpdateApplication)  // обновить заявку
+		appGroup.POST("", idempotent, h.CreateApplication) // создание заявки (с gRPC Auth проверкой)
+		appGroup.GET("", h.GetApplications)                // получить все заявки текущего пользователя
+		appGroup.GET("/export", h.ExportApplications)      // выгрузка заявок в CSV/XLSX/NDJSON
+		appGroup.GET("/:id", h.GetApplicationById)         // получить заявку по ID
+		appGroup.DELETE("/:id", h.DeleteApplication)       // удалить заявку
+		appGroup.PATCH("/:id", h.UpdateApplication)        // обновить заявку
+
+		// «кастомные методы» коллекции: /api/applications:batch
+		api.POST("/applications:action", middleware.AuthMiddleware(), applicationScope, h.ApplicationsAction)
+
+		// SSE-поток изменений заявок; EventSource не умеет заголовки, поэтому токен можно передать в query
+		if opts.Events != nil {
+			sh := &handlers.StreamHandler{Events: opts.Events, Heartbeat: opts.SSEHeartbeat, Shutdown: opts.Shutdown}
+			api.GET("/applications/stream", middleware.TokenFromQuery("access_token"), middleware.AuthMiddleware(), applicationScope, sh.StreamEvents)
+		}
 	}
 }
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"shopflow/application/handlers"
+	"shopflow/application/middleware"
+	"shopflow/application/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterDashboardRoutes регистрирует WebSocket-канал дашбордов бэк-офиса (только для сотрудников).
+// Браузерный WebSocket не умеет заголовки, поэтому токен можно передать в query.
+func RegisterDashboardRoutes(r *gin.Engine, hub *services.DashboardHub, allowedOrigins []string, shutdown <-chan struct{}) {
+	h := &handlers.DashboardHandler{Hub: hub, AllowedOrigins: allowedOrigins, Shutdown: shutdown}
+
+	r.GET("/api/dashboard/ws",
+		middleware.TokenFromQuery("access_token"),
+		middleware.AuthMiddleware(),
+		middleware.RequireRole(middleware.RoleStaff, middleware.RoleAdmin),
+		h.Dashboard,
+	)
+}
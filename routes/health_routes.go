@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"shopflow/application/handlers"
+	"shopflow/application/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterHealthRoutes регистрирует пробы для оркестратора (без авторизации)
+func RegisterHealthRoutes(r *gin.Engine, checks *health.Registry, stopping func() bool) {
+	h := &handlers.HealthHandler{Checks: checks, Stopping: stopping}
+
+	r.GET("/livez", h.Livez)   // процесс жив
+	r.GET("/readyz", h.Readyz) // зависимости доступны, можно слать трафик
+}
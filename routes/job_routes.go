@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"shopflow/application/handlers"
+	"shopflow/application/middleware"
+	"shopflow/application/models"
+	"shopflow/application/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterJobRoutes регистрирует маршруты фоновых задач (прогресс, отмена, скачивание результата)
+func RegisterJobRoutes(r *gin.Engine, jobSvc *services.JobService) {
+	jobs := r.Group("/api/jobs")
+	jobs.Use(middleware.AuthMiddleware(),
+		middleware.RequireScope(models.APIKeyScopeApplicationsRead, models.APIKeyScopeApplicationsWrite))
+
+	h := &handlers.JobHandler{Jobs: jobSvc}
+
+	jobs.GET("/:id", h.GetJob)                     // статус и прогресс задачи
+	jobs.POST("/:id/cancel", h.CancelJob)          // отмена задачи
+	jobs.GET("/:id/download", h.DownloadJobResult) // файл фоновой выгрузки
+}
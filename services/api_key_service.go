@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"shopflow/application/logging"
+	"shopflow/application/models"
+	"shopflow/application/repository"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidAPIKey — некорректные параметры выпуска ключа
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// apiKeyPrefix — начало всех ключей: по нему ключ легко найти в логах и отличить от JWT
+const apiKeyPrefix = "sfk_"
+
+// apiKeyScopes — области, которые можно выдать ключу
+var apiKeyScopes = map[string]bool{
+	models.APIKeyScopeApplicationsRead:  true,
+	models.APIKeyScopeApplicationsWrite: true,
+	models.APIKeyScopeApplicationsAll:   true,
+	models.APIKeyScopeAdmin:             true,
+}
+
+// APIKeyOptions — настройки проверки API-ключей
+type APIKeyOptions struct {
+	// CacheTTL — сколько помнить результат поиска ключа (0 — каждый запрос идёт в базу).
+	// Отзыв на других репликах вступает в силу не позже чем через CacheTTL.
+	CacheTTL  time.Duration
+	CacheSize int
+	// TouchInterval — last_used_at обновляется не чаще этого
+	TouchInterval time.Duration
+}
+
+// DefaultAPIKeyOptions — настройки по умолчанию
+func DefaultAPIKeyOptions() APIKeyOptions {
+	return APIKeyOptions{
+		CacheTTL:      30 * time.Second,
+		CacheSize:     1000,
+		TouchInterval: time.Minute,
+	}
+}
+
+// apiKeyEntry — результат поиска ключа; key == nil — ключа нет
+type apiKeyEntry struct {
+	key     *models.APIKey
+	expires time.Time
+}
+
+// APIKeyService выпускает и проверяет API-ключи сервисов. В базе хранится только
+// SHA-256 ключа: ключ случайный и длинный, перебор по хэшу бесполезен.
+type APIKeyService struct {
+	repo *repository.APIKeyRepository
+	opts APIKeyOptions
+
+	mu       sync.Mutex
+	cache    map[string]apiKeyEntry
+	lastUsed map[uint]time.Time
+}
+
+func NewAPIKeyService(repo *repository.APIKeyRepository, opts APIKeyOptions) *APIKeyService {
+	return &APIKeyService{
+		repo:     repo,
+		opts:     opts,
+		cache:    make(map[string]apiKeyEntry),
+		lastUsed: make(map[uint]time.Time),
+	}
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateAPIKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// CreateKey выпускает ключ. Сам ключ возвращается только здесь.
+func (s *APIKeyService) CreateKey(ctx context.Context, createdBy uint, req models.CreateAPIKeyRequest) (*models.APIKey, error) {
+	for _, scope := range req.Scopes {
+		if !apiKeyScopes[scope] {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKey, scope)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKey)
+	}
+
+	secret, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	key := &models.APIKey{
+		Name:      req.Name,
+		Prefix:    secret[:len(apiKeyPrefix)+8],
+		UserID:    req.UserID,
+		Email:     req.Email,
+		Scopes:    req.Scopes,
+		CreatedBy: createdBy,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.repo.Create(ctx, key, hashAPIKey(secret)); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "api key created", "component", "api_key",
+		"api_key_id", key.ID, "prefix", key.Prefix, "scopes", key.Scopes)
+	key.Key = secret
+	return key, nil
+}
+
+func (s *APIKeyService) ListKeys(ctx context.Context) ([]models.APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+// RevokeKey отзывает ключ. На этой реплике — сразу, на остальных — по истечении кэша.
+func (s *APIKeyService) RevokeKey(ctx context.Context, id uint) error {
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	for hash, e := range s.cache {
+		if e.key != nil && e.key.ID == id {
+			delete(s.cache, hash)
+		}
+	}
+	s.mu.Unlock()
+	slog.InfoContext(ctx, "api key revoked", "component", "api_key", "api_key_id", id)
+	return nil
+}
+
+// AuthenticateAPIKey возвращает действующий ключ или nil, если ключ неизвестен,
+// отозван или истёк. Ошибка — только если проверить ключ не удалось.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, secret string) (*models.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, nil
+	}
+	key, err := s.lookup(ctx, hashAPIKey(secret))
+	if err != nil || key == nil {
+		return nil, err
+	}
+	now := time.Now()
+	if key.RevokedAt != nil {
+		slog.DebugContext(ctx, "revoked api key used", "component", "api_key", "api_key_id", key.ID)
+		return nil, nil
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		slog.DebugContext(ctx, "expired api key used", "component", "api_key", "api_key_id", key.ID)
+		return nil, nil
+	}
+	s.touch(ctx, key.ID, now)
+	return key, nil
+}
+
+func (s *APIKeyService) lookup(ctx context.Context, hash string) (*models.APIKey, error) {
+	now := time.Now()
+	s.mu.Lock()
+	e, ok := s.cache[hash]
+	s.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.key, nil
+	}
+
+	key, err := s.repo.GetByHash(ctx, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		key, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.CacheTTL > 0 && s.opts.CacheSize > 0 {
+		s.mu.Lock()
+		if len(s.cache) >= s.opts.CacheSize {
+			for h, e := range s.cache {
+				if !now.Before(e.expires) || len(s.cache) >= s.opts.CacheSize {
+					delete(s.cache, h)
+				}
+			}
+		}
+		s.cache[hash] = apiKeyEntry{key: key, expires: now.Add(s.opts.CacheTTL)}
+		s.mu.Unlock()
+	}
+	return key, nil
+}
+
+// touch обновляет last_used_at в фоне, не чаще TouchInterval на ключ
+func (s *APIKeyService) touch(ctx context.Context, id uint, now time.Time) {
+	s.mu.Lock()
+	if prev, ok := s.lastUsed[id]; ok && now.Sub(prev) < s.opts.TouchInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastUsed[id] = now
+	s.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := s.repo.TouchLastUsed(ctx, id, now); err != nil {
+			slog.WarnContext(ctx, "failed to update api key last use", "component", "api_key",
+				"api_key_id", id, logging.Err(err))
+		}
+	}()
+}
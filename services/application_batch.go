@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopflow/application/models"
+	"shopflow/application/repository"
+)
+
+var (
+	// ErrInvalidOperation — операция пакета не прошла валидацию
+	ErrInvalidOperation = errors.New("invalid operation")
+	// ErrBatchRolledBack — операция выполнилась, но откатилась из-за ошибки в другой операции
+	ErrBatchRolledBack = errors.New("rolled back: another operation in the batch failed")
+	// ErrBatchSkipped — операция не выполнялась, потому что пакет уже прерван
+	ErrBatchSkipped = errors.New("skipped: batch aborted by an earlier failure")
+	// ErrBatchForbidden — операция требует роли сотрудника
+	ErrBatchForbidden = errors.New("only staff can assign applications")
+)
+
+// BatchItemResult — результат одной операции пакета.
+// Для delete App — последнее состояние удалённой заявки (без текста и вложения).
+type BatchItemResult struct {
+	Op  string
+	App *models.Application
+	Err error
+}
+
+// ExecuteBatch выполняет операции пакета от имени userID; staff — может ли он назначать заявки.
+// В режиме atomic все операции выполняются в одной транзакции и при первой ошибке
+// пакет откатывается; в режиме independent каждая операция выполняется отдельно.
+func (s *ApplicationService) ExecuteBatch(ctx context.Context, userID uint, staff bool, req models.BatchRequest) ([]BatchItemResult, error) {
+	mode := req.Mode
+	if mode == "" {
+		mode = models.BatchModeAtomic
+	}
+
+	results := make([]BatchItemResult, len(req.Operations))
+	invalid := false
+	for i, op := range req.Operations {
+		results[i].Op = op.Op
+		if err := validateBatchOperation(op, staff); err != nil {
+			results[i].Err = err
+			invalid = true
+		}
+	}
+
+	if mode == models.BatchModeIndependent {
+		for i, op := range req.Operations {
+			if results[i].Err != nil {
+				continue
+			}
+			results[i].App, results[i].Err = applyBatchOperation(ctx, s.repo, userID, op)
+		}
+		return results, nil
+	}
+
+	// atomic: при невалидной операции ничего не выполняем
+	if invalid {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Err = ErrBatchSkipped
+			}
+		}
+		return results, nil
+	}
+
+	failed := -1
+	err := s.repo.InTx(ctx, func(tx *repository.ApplicationRepository) error {
+		for i, op := range req.Operations {
+			app, err := applyBatchOperation(ctx, tx, userID, op)
+			if err != nil {
+				results[i].Err = err
+				failed = i
+				return err
+			}
+			results[i].App = app
+		}
+		return nil
+	})
+	if err != nil {
+		if failed < 0 {
+			// ошибка BEGIN/COMMIT — не относится к конкретной операции
+			return nil, err
+		}
+		for i := range results {
+			switch {
+			case i < failed:
+				results[i].App, results[i].Err = nil, ErrBatchRolledBack
+			case i > failed:
+				results[i].Err = ErrBatchSkipped
+			}
+		}
+	}
+	return results, nil
+}
+
+func validateBatchOperation(op models.BatchOperation, staff bool) error {
+	switch op.Op {
+	case models.BatchOpCreate:
+		if op.Text == nil || *op.Text == "" {
+			return fmt.Errorf("%w: text is required", ErrInvalidOperation)
+		}
+		if op.FileURL == nil || *op.FileURL == "" {
+			return fmt.Errorf("%w: file_url is required", ErrInvalidOperation)
+		}
+		return nil
+	case models.BatchOpUpdate:
+		if op.Text == nil && op.Status == nil && op.FileURL == nil && op.AssigneeID == nil {
+			return fmt.Errorf("%w: no fields to update", ErrInvalidOperation)
+		}
+		if op.AssigneeID != nil && !staff {
+			return ErrBatchForbidden
+		}
+	case models.BatchOpTransition:
+		if op.Status == nil || *op.Status == "" {
+			return fmt.Errorf("%w: status is required", ErrInvalidOperation)
+		}
+	}
+	if op.ID == 0 {
+		return fmt.Errorf("%w: id is required", ErrInvalidOperation)
+	}
+	return nil
+}
+
+func applyBatchOperation(ctx context.Context, repo *repository.ApplicationRepository, userID uint, op models.BatchOperation) (*models.Application, error) {
+	switch op.Op {
+	case models.BatchOpCreate:
+		app := models.Application{
+			UserID:  userID,
+			Text:    *op.Text,
+			FileURL: *op.FileURL,
+			Status:  "new",
+		}
+		if op.Status != nil && *op.Status != "" {
+			app.Status = *op.Status
+		}
+		if err := repo.Create(ctx, &app); err != nil {
+			return nil, err
+		}
+		return &app, nil
+	case models.BatchOpUpdate:
+		return repo.UpdateApplication(ctx, models.UpdateApplicationRequest{
+			Text:       op.Text,
+			Status:     op.Status,
+			FileURL:    op.FileURL,
+			AssigneeID: op.AssigneeID,
+		}, op.ID, op.Version)
+	case models.BatchOpTransition:
+		return repo.UpdateApplication(ctx, models.UpdateApplicationRequest{Status: op.Status}, op.ID, op.Version)
+	case models.BatchOpDelete:
+		return repo.DeleteApplicationById(ctx, op.ID, op.Version)
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidOperation, op.Op)
+}
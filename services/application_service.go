@@ -2,7 +2,6 @@ package services
 
 import (
 	"context"
-	"fmt"
 	"shopflow/application/models"
 	"shopflow/application/publisher"
 	"shopflow/application/repository"
@@ -11,25 +10,17 @@ import (
 type ApplicationService struct {
 	repo      *repository.ApplicationRepository
 	publisher *publisher.ApplicationPublisher
-	auth      AuthClient
 }
 
-func NewApplicationService(repo *repository.ApplicationRepository, publisher *publisher.ApplicationPublisher, auth AuthClient) *ApplicationService {
+// NewApplicationService создаёт сервис заявок. Токен проверяется до вызова сервиса (AuthMiddleware).
+func NewApplicationService(repo *repository.ApplicationRepository, publisher *publisher.ApplicationPublisher) *ApplicationService {
 	return &ApplicationService{
 		repo:      repo,
 		publisher: publisher,
-		auth:      auth,
 	}
 }
 
-func (s *ApplicationService) CreateApplication(ctx context.Context, userID uint, token, text, fileURL, status string) (models.Application, error) {
-	if s.auth != nil {
-		valid, _, err := s.auth.VerifyToken(uint32(userID), token)
-		if err != nil || !valid {
-			return models.Application{}, fmt.Errorf("invalid token")
-		}
-	}
-
+func (s *ApplicationService) CreateApplication(ctx context.Context, userID uint, text, fileURL, status string) (models.Application, error) {
 	app := models.Application{
 		UserID:  userID,
 		Text:    text,
@@ -40,12 +31,12 @@ func (s *ApplicationService) CreateApplication(ctx context.Context, userID uint,
 		app.Status = "new"
 	}
 
-	if err := s.repo.Create(&app); err != nil {
+	if err := s.repo.Create(ctx, &app); err != nil {
 		return models.Application{}, err
 	}
 
 	if s.publisher != nil {
-		_ = s.publisher.PublishApplicationCreated(publisher.ApplicationCreatedMessage{
+		_ = s.publisher.PublishApplicationCreated(ctx, publisher.ApplicationCreatedMessage{
 			ID:     app.ID,
 			UserID: app.UserID,
 			Text:   app.Text,
@@ -56,23 +47,22 @@ func (s *ApplicationService) CreateApplication(ctx context.Context, userID uint,
 	return app, nil
 }
 
-func (s *ApplicationService) GetAll(userID uint) ([]models.Application, error) {
-	return s.repo.GetAll(userID)
+func (s *ApplicationService) GetAll(ctx context.Context, filter models.ApplicationFilter) ([]models.Application, error) {
+	return s.repo.GetAll(ctx, filter)
 }
 
-func (s *ApplicationService) GetApplicationById(id uint) (*models.Application, error) {
-	return s.repo.GetApplicationById(id)
+func (s *ApplicationService) GetApplicationById(ctx context.Context, id uint) (*models.Application, error) {
+	return s.repo.GetApplicationById(ctx, id)
 }
 
-func (s *ApplicationService) DeleteApplication(id uint) error {
-	return s.repo.DeleteApplicationById(id)
+// DeleteApplication удаляет заявку и возвращает её последнее состояние;
+// expectedVersion == 0 отключает проверку версии
+func (s *ApplicationService) DeleteApplication(ctx context.Context, id uint, expectedVersion int) (*models.Application, error) {
+	return s.repo.DeleteApplicationById(ctx, id, expectedVersion)
 }
 
-func (s *ApplicationService) UpdateApplication(req models.UpdateApplicationRequest, id uint) (*models.Application, error) {
-	app := models.Application{
-		Text:    req.Text,
-		FileURL: req.FileURL,
-		Status:  req.Status,
-	}
-	return s.repo.UpdateApplication(app, id)
+// UpdateApplication частично обновляет заявку (только переданные поля);
+// expectedVersion == 0 отключает проверку версии
+func (s *ApplicationService) UpdateApplication(ctx context.Context, req models.UpdateApplicationRequest, id uint, expectedVersion int) (*models.Application, error) {
+	return s.repo.UpdateApplication(ctx, req, id, expectedVersion)
 }
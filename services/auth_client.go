@@ -2,41 +2,265 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	mrand "math/rand/v2"
 	"time"
 
+	"shopflow/application/logging"
+	"shopflow/application/metrics"
 	"shopflow/application/proto/authpb"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type AuthClient interface {
-	VerifyToken(userID uint32, token string) (bool, string, error)
+	VerifyToken(ctx context.Context, userID uint32, token string) (bool, string, error)
+	// Ping проверяет доступность Auth сервиса (для /readyz)
+	Ping(ctx context.Context) error
+	// Close закрывает соединение с Auth сервисом
+	Close() error
+}
+
+// AuthClientOptions — настройки клиента Auth сервиса
+type AuthClientOptions struct {
+	// VerifyTimeout — предел одной попытки VerifyToken (0 — только дедлайн вызывающего)
+	VerifyTimeout time.Duration
+	// CacheTTL и CacheSize — кэш результатов проверки (0 — без кэша). Запись живёт
+	// не дольше срока действия токена; отзыв токена заметен не позже чем через CacheTTL.
+	CacheTTL  time.Duration
+	CacheSize int
+	// Retries — сколько раз повторить вызов после временной ошибки (Unavailable и т.п.)
+	Retries int
+	// RetryBackoff — задержка перед первым повтором; дальше удваивается, с джиттером
+	RetryBackoff time.Duration
+	// BreakerThreshold — после стольких неудачных проверок подряд вызовы Auth
+	// приостанавливаются на BreakerCooldown (0 — без circuit breaker)
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// FailOpen — считать токен действительным, если Auth недоступен: временная ошибка
+	// или открытый circuit breaker. Отказ Auth (Unauthenticated и т.п.) токен не пропускает.
+	// Имеет смысл, только когда подпись уже проверена локально.
+	FailOpen bool
+
+	TLS AuthTLSOptions
+	// KeepaliveTime — период ping'ов простаивающего соединения (0 — без них);
+	// KeepaliveTimeout — сколько ждать ответа, прежде чем считать соединение разорванным
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+	// LoadBalancing — политика балансировки между адресами Auth из DNS: round_robin или pick_first
+	LoadBalancing string
+}
+
+// DefaultAuthClientOptions — настройки по умолчанию
+func DefaultAuthClientOptions() AuthClientOptions {
+	return AuthClientOptions{
+		VerifyTimeout:    3 * time.Second,
+		CacheTTL:         time.Minute,
+		CacheSize:        10000,
+		Retries:          2,
+		RetryBackoff:     100 * time.Millisecond,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+		KeepaliveTime:    30 * time.Second,
+		KeepaliveTimeout: 10 * time.Second,
+		LoadBalancing:    "round_robin",
+	}
 }
 
 type authClientGRPC struct {
-	client authpb.AuthServiceClient
+	conn    *grpc.ClientConn
+	client  authpb.AuthServiceClient
+	opts    AuthClientOptions
+	cache   *verifyCache
+	breaker *circuitBreaker
 }
 
-func NewAuthClient(grpcAddr string) (AuthClient, error) {
-	conn, err := grpc.Dial(grpcAddr, grpc.WithInsecure())
+// NewAuthClient создаёт клиент Auth. Адрес разрешается через DNS ("dns:///auth:50051"
+// или "auth:50051"); при нескольких репликах вызовы распределяются по LoadBalancing.
+// Соединение устанавливается при первом вызове.
+func NewAuthClient(grpcAddr string, opts AuthClientOptions) (AuthClient, error) {
+	creds, err := authTransportCredentials(opts.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("auth TLS: %w", err)
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		// span на каждый вызов и traceparent в метаданных; проверки здоровья не трассируем
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))),
+	}
+	if opts.LoadBalancing != "" {
+		dialOpts = append(dialOpts, grpc.WithDefaultServiceConfig(
+			fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}]}`, opts.LoadBalancing)))
+	}
+	if opts.KeepaliveTime > 0 {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                opts.KeepaliveTime,
+			Timeout:             opts.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}))
+	}
+
+	conn, err := grpc.NewClient(grpcAddr, dialOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	client := authpb.NewAuthServiceClient(conn)
-	return &authClientGRPC{client: client}, nil
+	return &authClientGRPC{
+		conn:    conn,
+		client:  client,
+		opts:    opts,
+		cache:   newVerifyCache(opts.CacheTTL, opts.CacheSize),
+		breaker: newCircuitBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+	}, nil
+}
+
+// VerifyToken проверяет токен в Auth: сначала по кэшу, затем вызовом с повторами
+// через circuit breaker. Если Auth недоступен, результат определяет FailOpen.
+func (a *authClientGRPC) VerifyToken(ctx context.Context, userID uint32, token string) (bool, string, error) {
+	key := verifyCacheKey(userID, token)
+	if r, ok := a.cache.get(key); ok {
+		metrics.VerifyTokenCache(true)
+		return r.valid, r.email, nil
+	}
+	if a.cache != nil {
+		metrics.VerifyTokenCache(false)
+	}
+
+	valid, email, err := a.verify(ctx, userID, token)
+	if err != nil {
+		if ctx.Err() != nil {
+			// запрос отменён вызывающим — политика недоступности не применяется
+			return false, "", err
+		}
+		if rejectedAuthError(err) {
+			// Auth отклонил токен своим перехватчиком — это ответ, а не недоступность
+			slog.DebugContext(ctx, "token rejected by auth service", "component", "auth", logging.Err(err))
+			a.cache.put(key, token, verifyResult{valid: false})
+			return false, "", nil
+		}
+		if !retryableAuthError(err) && !errors.Is(err, ErrAuthCircuitOpen) {
+			// Auth ответил ошибкой, но доступен: FailOpen к такому ответу не относится
+			return false, "", err
+		}
+		if a.opts.FailOpen {
+			metrics.VerifyTokenUnavailable(metrics.PolicyFailOpen)
+			slog.WarnContext(ctx, "auth service unavailable, accepting token (fail-open)", "component", "auth", logging.Err(err))
+			return true, "", nil
+		}
+		metrics.VerifyTokenUnavailable(metrics.PolicyFailClosed)
+		return false, "", err
+	}
+	a.cache.put(key, token, verifyResult{valid: valid, email: email})
+	return valid, email, nil
 }
 
-func (a *authClientGRPC) VerifyToken(userID uint32, token string) (bool, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// verify вызывает Auth с повторами временных ошибок; учитывает результат в circuit breaker.
+// Неудачей для breaker считаются только временные ошибки: любой другой ответ значит, что Auth работает.
+func (a *authClientGRPC) verify(ctx context.Context, userID uint32, token string) (bool, string, error) {
+	if !a.breaker.allow() {
+		return false, "", ErrAuthCircuitOpen
+	}
+
+	backoff := a.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		valid, email, err := a.call(ctx, userID, token)
+		if err == nil {
+			a.breaker.record(true)
+			return valid, email, nil
+		}
+		if ctx.Err() != nil {
+			a.breaker.cancel()
+			return false, "", err
+		}
+		if !retryableAuthError(err) {
+			a.breaker.record(true)
+			return false, "", err
+		}
+		if attempt >= a.opts.Retries {
+			a.breaker.record(false)
+			return false, "", err
+		}
+
+		// джиттер 50–100% задержки, чтобы реплики не повторяли вызовы синхронно
+		delay := time.Duration(float64(backoff) * (0.5 + mrand.Float64()*0.5))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			a.breaker.cancel()
+			return false, "", ctx.Err()
+		}
+		backoff *= 2
+	}
+}
 
+// call — одна попытка VerifyToken
+func (a *authClientGRPC) call(ctx context.Context, userID uint32, token string) (bool, string, error) {
+	if a.opts.VerifyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.opts.VerifyTimeout)
+		defer cancel()
+	}
+
+	// токен передаётся и в теле, и в метаданных: Auth может проверять его своим перехватчиком
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+
+	start := time.Now()
 	resp, err := a.client.VerifyToken(ctx, &authpb.AuthRequest{
 		UserId: userID,
 		Token:  token,
 	})
 	if err != nil {
+		metrics.VerifyToken(start, metrics.VerifyError)
 		return false, "", err
 	}
+	result := metrics.VerifyValid
+	if !resp.Valid {
+		result = metrics.VerifyInvalid
+	}
+	metrics.VerifyToken(start, result)
 	return resp.Valid, resp.Email, nil
 }
+
+func (a *authClientGRPC) Close() error {
+	return a.conn.Close()
+}
+
+// Ping опрашивает стандартный gRPC health-сервис Auth. Если Auth его не реализует,
+// достаточно того, что соединение установлено.
+func (a *authClientGRPC) Ping(ctx context.Context) error {
+	resp, err := healthpb.NewHealthClient(a.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return a.waitReady(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("auth service is %s", resp.Status)
+	}
+	return nil
+}
+
+func (a *authClientGRPC) waitReady(ctx context.Context) error {
+	a.conn.Connect()
+	for {
+		state := a.conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !a.conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("auth connection is %s", state)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"shopflow/application/metrics"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrAuthCircuitOpen — Auth недавно не отвечал, вызовы временно не выполняются
+var ErrAuthCircuitOpen = errors.New("auth service circuit breaker is open")
+
+// verifyResult — ответ Auth на проверку токена
+type verifyResult struct {
+	valid   bool
+	email   string
+	expires time.Time
+}
+
+// verifyCache — кэш результатов проверки токенов. Ключ — хэш токена (сам токен
+// в памяти не хранится), запись живёт не дольше ttl и не дольше срока действия токена.
+type verifyCache struct {
+	ttl  time.Duration
+	size int
+
+	mu      sync.Mutex
+	entries map[string]verifyResult
+}
+
+func newVerifyCache(ttl time.Duration, size int) *verifyCache {
+	if ttl <= 0 || size <= 0 {
+		return nil
+	}
+	return &verifyCache{ttl: ttl, size: size, entries: make(map[string]verifyResult)}
+}
+
+func verifyCacheKey(userID uint32, token string) string {
+	sum := sha256.Sum256([]byte(strconv.FormatUint(uint64(userID), 10) + ":" + token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *verifyCache) get(key string) (verifyResult, bool) {
+	if c == nil {
+		return verifyResult{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.entries[key]
+	if !ok {
+		return verifyResult{}, false
+	}
+	if !time.Now().Before(r.expires) {
+		delete(c.entries, key)
+		return verifyResult{}, false
+	}
+	return r, true
+}
+
+func (c *verifyCache) put(key, token string, r verifyResult) {
+	if c == nil {
+		return
+	}
+	now := time.Now()
+	r.expires = now.Add(c.ttl)
+	if exp := tokenExpiry(token); !exp.IsZero() && exp.Before(r.expires) {
+		r.expires = exp
+	}
+	if !now.Before(r.expires) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.size {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	// кэш полон живыми записями — вытесняем произвольную
+	for k := range c.entries {
+		if len(c.entries) < c.size {
+			break
+		}
+		delete(c.entries, k)
+	}
+	c.entries[key] = r
+}
+
+// tokenExpiry — claim exp без проверки подписи (нулевое время, если его нет)
+func tokenExpiry(token string) time.Time {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return time.Time{}
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}
+	}
+	return exp.Time
+}
+
+// circuitBreaker перестаёт обращаться к Auth после threshold ошибок подряд.
+// Через cooldown пропускается один пробный вызов: успех закрывает breaker, ошибка — снова открывает.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	metrics.AuthBreakerState(metrics.BreakerClosed)
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow — можно ли выполнить вызов сейчас
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case metrics.BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(metrics.BreakerHalfOpen)
+		b.probing = true
+		return true
+	case metrics.BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record учитывает результат вызова, разрешённого allow
+func (b *circuitBreaker) record(ok bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if ok {
+		b.failures = 0
+		b.setState(metrics.BreakerClosed)
+		return
+	}
+	b.failures++
+	if b.state == metrics.BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		b.setState(metrics.BreakerOpen)
+	}
+}
+
+// cancel — вызов прерван вызывающим, о доступности Auth он ничего не говорит
+func (b *circuitBreaker) cancel() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func (b *circuitBreaker) setState(state int) {
+	if b.state != state {
+		b.state = state
+		metrics.AuthBreakerState(state)
+	}
+}
+
+// rejectedAuthError — Auth окончательно отклонил токен (например, перехватчиком по метаданным)
+func rejectedAuthError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied, codes.InvalidArgument:
+		return true
+	}
+	return false
+}
+
+// retryableAuthError — временная ошибка, после которой вызов стоит повторить
+func retryableAuthError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
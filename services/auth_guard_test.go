@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"shopflow/application/metrics"
+	"shopflow/application/proto/authpb"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testCooldown = 20 * time.Millisecond
+
+// breakerStep — действие над circuitBreaker и ожидаемое состояние после него
+type breakerStep struct {
+	do        string // allow, ok, fail, cancel, wait
+	wantAllow bool   // для allow
+	wantState int
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{
+			name: "stays closed below threshold",
+			steps: []breakerStep{
+				{do: "allow", wantAllow: true, wantState: metrics.BreakerClosed},
+				{do: "fail", wantState: metrics.BreakerClosed},
+				{do: "allow", wantAllow: true, wantState: metrics.BreakerClosed},
+				{do: "fail", wantState: metrics.BreakerClosed},
+				{do: "allow", wantAllow: true, wantState: metrics.BreakerClosed},
+			},
+		},
+		{
+			name: "success resets failure count",
+			steps: []breakerStep{
+				{do: "fail", wantState: metrics.BreakerClosed},
+				{do: "fail", wantState: metrics.BreakerClosed},
+				{do: "ok", wantState: metrics.BreakerClosed},
+				{do: "fail", wantState: metrics.BreakerClosed},
+				{do: "fail", wantState: metrics.BreakerClosed},
+				{do: "allow", wantAllow: true, wantState: metrics.BreakerClosed},
+			},
+		},
+		{
+			name: "opens at threshold and rejects during cooldown",
+			steps: []breakerStep{
+				{do: "fail", wantState: metrics.BreakerClosed},
+				{do: "fail", wantState: metrics.BreakerClosed},
+				{do: "fail", wantState: metrics.BreakerOpen},
+				{do: "allow", wantAllow: false, wantState: metrics.BreakerOpen},
+			},
+		},
+		{
+			name: "half-open lets one probe through and closes on success",
+			steps: []breakerStep{
+				{do: "fail"}, {do: "fail"}, {do: "fail", wantState: metrics.BreakerOpen},
+				{do: "wait", wantState: metrics.BreakerOpen},
+				{do: "allow", wantAllow: true, wantState: metrics.BreakerHalfOpen},
+				{do: "allow", wantAllow: false, wantState: metrics.BreakerHalfOpen},
+				{do: "ok", wantState: metrics.BreakerClosed},
+				{do: "allow", wantAllow: true, wantState: metrics.BreakerClosed},
+			},
+		},
+		{
+			name: "failed probe reopens immediately",
+			steps: []breakerStep{
+				{do: "fail"}, {do: "fail"}, {do: "fail", wantState: metrics.BreakerOpen},
+				{do: "wait", wantState: metrics.BreakerOpen},
+				{do: "allow", wantAllow: true, wantState: metrics.BreakerHalfOpen},
+				{do: "fail", wantState: metrics.BreakerOpen},
+				{do: "allow", wantAllow: false, wantState: metrics.BreakerOpen},
+			},
+		},
+		{
+			name: "cancelled probe frees the slot",
+			steps: []breakerStep{
+				{do: "fail"}, {do: "fail"}, {do: "fail", wantState: metrics.BreakerOpen},
+				{do: "wait", wantState: metrics.BreakerOpen},
+				{do: "allow", wantAllow: true, wantState: metrics.BreakerHalfOpen},
+				{do: "cancel", wantState: metrics.BreakerHalfOpen},
+				{do: "allow", wantAllow: true, wantState: metrics.BreakerHalfOpen},
+				{do: "ok", wantState: metrics.BreakerClosed},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(3, testCooldown)
+			for i, step := range tt.steps {
+				switch step.do {
+				case "allow":
+					if got := b.allow(); got != step.wantAllow {
+						t.Fatalf("step %d: allow() = %v, want %v", i, got, step.wantAllow)
+					}
+				case "ok":
+					b.record(true)
+				case "fail":
+					b.record(false)
+				case "cancel":
+					b.cancel()
+				case "wait":
+					time.Sleep(testCooldown)
+				default:
+					t.Fatalf("step %d: unknown action %q", i, step.do)
+				}
+				if b.state != step.wantState {
+					t.Fatalf("step %d (%s): state = %d, want %d", i, step.do, b.state, step.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(0, testCooldown)
+	for i := 0; i < 10; i++ {
+		b.record(false)
+	}
+	if !b.allow() {
+		t.Fatal("disabled breaker rejected a call")
+	}
+}
+
+func testToken(t *testing.T, exp time.Time) string {
+	t.Helper()
+	claims := jwt.MapClaims{"user_id": 1}
+	if !exp.IsZero() {
+		claims["exp"] = exp.Unix()
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyCache(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		tokenExp time.Time
+		wait     time.Duration
+		wantHit  bool
+	}{
+		{name: "hit within ttl", ttl: time.Minute, tokenExp: now.Add(time.Hour), wantHit: true},
+		{name: "token without exp uses ttl", ttl: time.Minute, wantHit: true},
+		{name: "miss after ttl", ttl: 20 * time.Millisecond, tokenExp: now.Add(time.Hour), wait: 30 * time.Millisecond},
+		{name: "not cached past token expiry", ttl: time.Hour, tokenExp: now.Add(-time.Second)},
+		{name: "capped by token expiry", ttl: time.Hour, tokenExp: now.Add(1100 * time.Millisecond), wait: 1200 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newVerifyCache(tt.ttl, 10)
+			token := testToken(t, tt.tokenExp)
+			key := verifyCacheKey(1, token)
+			c.put(key, token, verifyResult{valid: true, email: "user@example.com"})
+			time.Sleep(tt.wait)
+
+			r, ok := c.get(key)
+			if ok != tt.wantHit {
+				t.Fatalf("hit = %v, want %v", ok, tt.wantHit)
+			}
+			if ok && (!r.valid || r.email != "user@example.com") {
+				t.Errorf("cached result = %+v", r)
+			}
+		})
+	}
+}
+
+func TestVerifyCacheKeyDependsOnUser(t *testing.T) {
+	token := testToken(t, time.Now().Add(time.Hour))
+	c := newVerifyCache(time.Minute, 10)
+	c.put(verifyCacheKey(1, token), token, verifyResult{valid: true})
+
+	if _, ok := c.get(verifyCacheKey(2, token)); ok {
+		t.Fatal("result cached for user 1 returned for user 2")
+	}
+}
+
+func TestVerifyCacheSizeLimit(t *testing.T) {
+	c := newVerifyCache(time.Minute, 3)
+	for i := 0; i < 10; i++ {
+		token := testToken(t, time.Now().Add(time.Hour))
+		c.put(verifyCacheKey(uint32(i), token+fmt.Sprint(i)), token, verifyResult{valid: true})
+	}
+	if n := len(c.entries); n > 3 {
+		t.Fatalf("cache holds %d entries, limit 3", n)
+	}
+}
+
+func TestVerifyCacheDisabled(t *testing.T) {
+	c := newVerifyCache(0, 10)
+	token := testToken(t, time.Now().Add(time.Hour))
+	c.put("key", token, verifyResult{valid: true})
+	if _, ok := c.get("key"); ok {
+		t.Fatal("disabled cache returned a result")
+	}
+}
+
+// authStub — Auth сервис, отвечающий заданной ошибкой или результатом
+type authStub struct {
+	err   error
+	valid bool
+	calls int
+}
+
+func (s *authStub) VerifyToken(ctx context.Context, in *authpb.AuthRequest, opts ...grpc.CallOption) (*authpb.AuthResponse, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &authpb.AuthResponse{Valid: s.valid, Email: "user@example.com"}, nil
+}
+
+func newTestAuthClient(stub *authStub, failOpen bool) *authClientGRPC {
+	return &authClientGRPC{
+		client:  stub,
+		opts:    AuthClientOptions{Retries: 1, RetryBackoff: time.Millisecond, FailOpen: failOpen},
+		breaker: newCircuitBreaker(3, time.Hour),
+	}
+}
+
+func TestVerifyTokenFailOpen(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		valid     bool
+		failOpen  bool
+		wantValid bool
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "unauthenticated is rejected with fail-open", err: status.Error(codes.Unauthenticated, "token revoked"), failOpen: true, wantCalls: 1},
+		{name: "permission denied is rejected with fail-open", err: status.Error(codes.PermissionDenied, "denied"), failOpen: true, wantCalls: 1},
+		{name: "invalid argument is rejected with fail-open", err: status.Error(codes.InvalidArgument, "bad token"), failOpen: true, wantCalls: 1},
+		{name: "unauthenticated is rejected without fail-open", err: status.Error(codes.Unauthenticated, "token revoked"), wantCalls: 1},
+		{name: "internal error is not fail-open", err: status.Error(codes.Internal, "boom"), failOpen: true, wantErr: true, wantCalls: 1},
+		{name: "unavailable accepted with fail-open", err: status.Error(codes.Unavailable, "down"), failOpen: true, wantValid: true, wantCalls: 2},
+		{name: "unavailable fails closed", err: status.Error(codes.Unavailable, "down"), wantErr: true, wantCalls: 2},
+		{name: "valid token", valid: true, failOpen: true, wantValid: true, wantCalls: 1},
+		{name: "invalid token", failOpen: true, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &authStub{err: tt.err, valid: tt.valid}
+			a := newTestAuthClient(stub, tt.failOpen)
+
+			valid, _, err := a.VerifyToken(context.Background(), 1, "token")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v", valid, tt.wantValid)
+			}
+			if stub.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", stub.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenBreakerCountsOnlyTransientErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantState int
+	}{
+		{name: "rejections keep breaker closed", err: status.Error(codes.Unauthenticated, "token revoked"), wantState: metrics.BreakerClosed},
+		{name: "internal errors keep breaker closed", err: status.Error(codes.Internal, "boom"), wantState: metrics.BreakerClosed},
+		{name: "unavailable opens breaker", err: status.Error(codes.Unavailable, "down"), wantState: metrics.BreakerOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthClient(&authStub{err: tt.err}, true)
+			for i := 0; i < 5; i++ {
+				_, _, _ = a.VerifyToken(context.Background(), 1, "token")
+			}
+			if a.breaker.state != tt.wantState {
+				t.Errorf("state = %d, want %d", a.breaker.state, tt.wantState)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"shopflow/application/logging"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// AuthTLSOptions — TLS соединения с Auth сервисом
+type AuthTLSOptions struct {
+	// Enabled — шифровать соединение; без него используется h2c (только для локальной разработки)
+	Enabled bool
+	// CAFile — bundle корневых сертификатов Auth (пусто — системные)
+	CAFile string
+	// CertFile и KeyFile — клиентский сертификат для mTLS (пусто — без него)
+	CertFile string
+	KeyFile  string
+	// ServerName — имя в сертификате Auth, если отличается от хоста в адресе
+	ServerName string
+}
+
+// authTransportCredentials собирает транспорт gRPC. Файлы сертификатов перечитываются
+// при очередном TLS-рукопожатии, если изменились (ротация без перезапуска).
+func authTransportCredentials(o AuthTLSOptions) (credentials.TransportCredentials, error) {
+	if !o.Enabled {
+		return insecure.NewCredentials(), nil
+	}
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, errors.New("both client certificate and key are required for mTLS")
+	}
+
+	files := &tlsFiles{opts: o}
+	if err := files.load(); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: o.ServerName}
+	if o.CertFile != "" {
+		cfg.GetClientCertificate = files.clientCertificate
+	}
+	if o.CAFile != "" {
+		// сертификат сервера проверяем сами по актуальному bundle
+		// (стандартная проверка использовала бы RootCAs, зафиксированные при запуске)
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = files.verifyConnection
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// tlsFiles хранит сертификаты из файлов и перечитывает их при изменении
+type tlsFiles struct {
+	opts AuthTLSOptions
+
+	mu      sync.Mutex
+	modTime map[string]time.Time
+	cert    *tls.Certificate
+	roots   *x509.CertPool
+}
+
+// load читает все файлы; при ошибке прежние сертификаты остаются в силе
+func (f *tlsFiles) load() error {
+	modTime := make(map[string]time.Time)
+	for _, name := range []string{f.opts.CAFile, f.opts.CertFile, f.opts.KeyFile} {
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		modTime[name] = info.ModTime()
+	}
+
+	var cert *tls.Certificate
+	if f.opts.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(f.opts.CertFile, f.opts.KeyFile)
+		if err != nil {
+			return fmt.Errorf("load client certificate: %w", err)
+		}
+		cert = &c
+	}
+	var roots *x509.CertPool
+	if f.opts.CAFile != "" {
+		pem, err := os.ReadFile(f.opts.CAFile)
+		if err != nil {
+			return err
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", f.opts.CAFile)
+		}
+	}
+
+	f.mu.Lock()
+	f.modTime, f.cert, f.roots = modTime, cert, roots
+	f.mu.Unlock()
+	return nil
+}
+
+// reloadIfChanged перечитывает файлы, если у какого-то из них сменилось время изменения
+func (f *tlsFiles) reloadIfChanged() {
+	f.mu.Lock()
+	changed := false
+	for name, prev := range f.modTime {
+		if info, err := os.Stat(name); err == nil && !info.ModTime().Equal(prev) {
+			changed = true
+			break
+		}
+	}
+	f.mu.Unlock()
+	if !changed {
+		return
+	}
+	// сертификат и ключ могут обновиться не одновременно — тогда повторим при следующем рукопожатии
+	if err := f.load(); err != nil {
+		slog.Warn("failed to reload auth TLS certificates, keeping previous", "component", "auth", logging.Err(err))
+		return
+	}
+	slog.Info("auth TLS certificates reloaded", "component", "auth")
+}
+
+func (f *tlsFiles) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	f.reloadIfChanged()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cert, nil
+}
+
+func (f *tlsFiles) verifyConnection(cs tls.ConnectionState) error {
+	f.reloadIfChanged()
+	f.mu.Lock()
+	roots := f.roots
+	f.mu.Unlock()
+
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("auth server presented no certificate")
+	}
+	serverName := f.opts.ServerName
+	if serverName == "" {
+		serverName = cs.ServerName
+	}
+	if serverName == "" {
+		// SNI не отправляется для IP-адресов
+		return errors.New("auth TLS server name is required when connecting by IP address")
+	}
+
+	opts := x509.VerifyOptions{DNSName: serverName, Roots: roots, Intermediates: x509.NewCertPool()}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
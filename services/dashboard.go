@@ -0,0 +1,376 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"shopflow/application/logging"
+	"shopflow/application/models"
+	"shopflow/application/repository"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrInvalidTopic — неизвестная тема подписки дашборда
+var ErrInvalidTopic = errors.New("invalid topic")
+
+// Темы подписки дашбордов
+const (
+	TopicAll          = "all"
+	TopicUnassigned   = "unassigned"
+	topicStatusPrefix = "status:"
+	topicAssignee     = "assignee:"
+	topicAssigneeMe   = "assignee:me"
+)
+
+// Записи присутствия продлеваются каждые presenceTouchInterval и считаются
+// устаревшими (реплика упала, не успев их удалить) через presenceTTL.
+const (
+	presenceTouchInterval = 30 * time.Second
+	presenceTTL           = 90 * time.Second
+)
+
+// dashboardSessionBuffer — очередь служебных сообщений одной сессии
+const dashboardSessionBuffer = 32
+
+// DashboardSession — одно WebSocket-подключение сотрудника.
+// Events — поток всех событий заявок (фильтруется через Matches),
+// Out — служебные сообщения (присутствие, подтверждения).
+type DashboardSession struct {
+	ID     string
+	UserID uint
+	Email  string
+	Events *EventSubscriber
+	Out    chan models.DashboardServerMessage
+
+	mu      sync.Mutex
+	topics  map[string]bool
+	viewing map[uint]bool
+}
+
+// Subscribe добавляет темы и возвращает текущий список. "assignee:me" раскрывается в ID сотрудника.
+func (s *DashboardSession) Subscribe(topics []string) ([]string, error) {
+	normalized := make([]string, 0, len(topics))
+	for _, t := range topics {
+		n, err := s.normalizeTopic(t)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, n)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range normalized {
+		s.topics[t] = true
+	}
+	return s.topicList(), nil
+}
+
+// Unsubscribe убирает темы и возвращает оставшиеся
+func (s *DashboardSession) Unsubscribe(topics []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range topics {
+		if n, err := s.normalizeTopic(t); err == nil {
+			delete(s.topics, n)
+		}
+	}
+	return s.topicList()
+}
+
+func (s *DashboardSession) topicList() []string {
+	list := make([]string, 0, len(s.topics))
+	for t := range s.topics {
+		list = append(list, t)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (s *DashboardSession) normalizeTopic(t string) (string, error) {
+	t = strings.TrimSpace(t)
+	switch {
+	case t == TopicAll, t == TopicUnassigned:
+		return t, nil
+	case t == topicAssigneeMe:
+		return topicAssignee + strconv.FormatUint(uint64(s.UserID), 10), nil
+	case strings.HasPrefix(t, topicStatusPrefix) && len(t) > len(topicStatusPrefix):
+		return t, nil
+	case strings.HasPrefix(t, topicAssignee):
+		if id, err := strconv.ParseUint(t[len(topicAssignee):], 10, 32); err == nil && id > 0 {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidTopic, t)
+}
+
+// dashboardEventData — поля события, по которым выбираются темы
+type dashboardEventData struct {
+	ID                 uint   `json:"id"`
+	Status             string `json:"status"`
+	AssigneeID         uint   `json:"assignee_id"`
+	PreviousStatus     string `json:"previous_status"`
+	PreviousAssigneeID *uint  `json:"previous_assignee_id"`
+}
+
+// Matches — событие относится к одной из тем сессии или к заявке, которую сессия открыла.
+// Темы сверяются и с прежними статусом и назначением: подписчик на status:new должен
+// узнать, что заявка из new ушла.
+func (s *DashboardSession) Matches(e *models.ApplicationEvent) bool {
+	var data dashboardEventData
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.topics[TopicAll] || s.viewing[data.ID] {
+		return true
+	}
+	if s.matchesState(data.Status, data.AssigneeID) {
+		return true
+	}
+	return data.PreviousStatus != "" && data.PreviousAssigneeID != nil &&
+		s.matchesState(data.PreviousStatus, *data.PreviousAssigneeID)
+}
+
+// matchesState — заявка со статусом status и назначением assigneeID (0 — не назначена)
+// попадает в темы сессии; вызывается под s.mu
+func (s *DashboardSession) matchesState(status string, assigneeID uint) bool {
+	switch {
+	case status != "" && s.topics[topicStatusPrefix+status]:
+		return true
+	case assigneeID != 0 && s.topics[topicAssignee+strconv.FormatUint(uint64(assigneeID), 10)]:
+		return true
+	case assigneeID == 0 && status != "" && s.topics[TopicUnassigned]:
+		return true
+	}
+	return false
+}
+
+func (s *DashboardSession) isViewing(appID uint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.viewing[appID]
+}
+
+// push отправляет служебное сообщение, не блокируясь на медленном клиенте
+func (s *DashboardSession) push(msg models.DashboardServerMessage) {
+	select {
+	case s.Out <- msg:
+	default:
+		slog.Warn("session is not reading, dropping message", "component", "dashboard", "session_id", s.ID, "type", msg.Type)
+	}
+}
+
+// DashboardHub управляет WebSocket-сессиями бэк-офиса: события заявок берутся
+// из EventStream, присутствие хранится в Postgres и расходится между репликами
+// через LISTEN/NOTIFY.
+type DashboardHub struct {
+	events   *EventStream
+	presence *repository.PresenceRepository
+	dsn      string
+
+	mu       sync.Mutex
+	sessions map[*DashboardSession]struct{}
+	active   sync.WaitGroup
+}
+
+func NewDashboardHub(events *EventStream, presence *repository.PresenceRepository, dsn string) *DashboardHub {
+	return &DashboardHub{
+		events:   events,
+		presence: presence,
+		dsn:      dsn,
+		sessions: make(map[*DashboardSession]struct{}),
+	}
+}
+
+// Open регистрирует новую сессию сотрудника
+func (h *DashboardHub) Open(userID uint, email string) (*DashboardSession, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	sess := &DashboardSession{
+		ID:      hex.EncodeToString(b),
+		UserID:  userID,
+		Email:   email,
+		Events:  h.events.Subscribe(0, true),
+		Out:     make(chan models.DashboardServerMessage, dashboardSessionBuffer),
+		topics:  map[string]bool{},
+		viewing: map[uint]bool{},
+	}
+
+	h.mu.Lock()
+	h.sessions[sess] = struct{}{}
+	h.mu.Unlock()
+	h.active.Add(1)
+	return sess, nil
+}
+
+// Close снимает сессию: отписывает от событий и убирает её присутствие.
+// Присутствие убирается и после отмены ctx (клиент отключился, сервер останавливается).
+func (h *DashboardHub) Close(ctx context.Context, sess *DashboardSession) {
+	defer h.active.Done()
+	ctx = context.WithoutCancel(ctx)
+	h.mu.Lock()
+	delete(h.sessions, sess)
+	h.mu.Unlock()
+	h.events.Unsubscribe(sess.Events)
+
+	sess.mu.Lock()
+	viewing := make([]uint, 0, len(sess.viewing))
+	for id := range sess.viewing {
+		viewing = append(viewing, id)
+	}
+	sess.viewing = map[uint]bool{}
+	sess.mu.Unlock()
+
+	for _, id := range viewing {
+		if err := h.presence.Leave(ctx, id, sess.ID); err != nil {
+			slog.ErrorContext(ctx, "failed to remove presence", "component", "dashboard", "application_id", id, logging.Err(err))
+		}
+	}
+}
+
+// Drain ждёт закрытия всех сессий (WebSocket-соединения не отслеживаются
+// http.Server.Shutdown, поэтому их дожидаемся отдельно)
+func (h *DashboardHub) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// View отмечает, что сотрудник открыл заявку; остальные зрители получат обновлённый список
+func (h *DashboardHub) View(ctx context.Context, sess *DashboardSession, appID uint) error {
+	sess.mu.Lock()
+	sess.viewing[appID] = true
+	sess.mu.Unlock()
+	return h.presence.Join(ctx, appID, sess.ID, sess.UserID, sess.Email)
+}
+
+// Leave отмечает, что сотрудник закрыл заявку
+func (h *DashboardHub) Leave(ctx context.Context, sess *DashboardSession, appID uint) error {
+	sess.mu.Lock()
+	delete(sess.viewing, appID)
+	sess.mu.Unlock()
+	return h.presence.Leave(ctx, appID, sess.ID)
+}
+
+// Run слушает изменения присутствия и продлевает записи локальных сессий. Завершается с ctx.
+func (h *DashboardHub) Run(ctx context.Context) {
+	listener := pq.NewListener(h.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error("listener error", "component", "dashboard", logging.Err(err))
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(repository.PresenceChannel); err != nil {
+		slog.Error("failed to listen for presence", "component", "dashboard", logging.Err(err))
+		return
+	}
+
+	touch := time.NewTicker(presenceTouchInterval)
+	defer touch.Stop()
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			if n == nil {
+				h.refreshAllPresence(ctx)
+				continue
+			}
+			id, err := strconv.ParseUint(n.Extra, 10, 32)
+			if err != nil {
+				continue
+			}
+			h.broadcastPresence(ctx, uint(id))
+		case <-touch.C:
+			if err := h.presence.Touch(ctx, h.sessionIDs()); err != nil {
+				slog.Error("failed to touch presence", "component", "dashboard", logging.Err(err))
+			}
+			if err := h.presence.PurgeStale(ctx, presenceTTL); err != nil {
+				slog.Error("failed to purge stale presence", "component", "dashboard", logging.Err(err))
+			}
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
+
+func (h *DashboardHub) sessionIDs() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([]string, 0, len(h.sessions))
+	for sess := range h.sessions {
+		ids = append(ids, sess.ID)
+	}
+	return ids
+}
+
+// broadcastPresence рассылает актуальный список зрителей заявки локальным сессиям, открывшим её
+func (h *DashboardHub) broadcastPresence(ctx context.Context, appID uint) {
+	var targets []*DashboardSession
+	h.mu.Lock()
+	for sess := range h.sessions {
+		if sess.isViewing(appID) {
+			targets = append(targets, sess)
+		}
+	}
+	h.mu.Unlock()
+	if len(targets) == 0 {
+		return
+	}
+
+	viewers, err := h.presence.Viewers(ctx, appID)
+	if err != nil {
+		slog.Error("failed to load viewers", "component", "dashboard", "application_id", appID, logging.Err(err))
+		return
+	}
+	msg := models.DashboardServerMessage{Type: models.DashboardPresence, ApplicationID: appID, Viewers: viewers}
+	for _, sess := range targets {
+		sess.push(msg)
+	}
+}
+
+// refreshAllPresence пересылает присутствие по всем открытым заявкам
+// (после переподключения LISTEN уведомления за время разрыва потеряны)
+func (h *DashboardHub) refreshAllPresence(ctx context.Context) {
+	ids := map[uint]bool{}
+	h.mu.Lock()
+	for sess := range h.sessions {
+		sess.mu.Lock()
+		for id := range sess.viewing {
+			ids[id] = true
+		}
+		sess.mu.Unlock()
+	}
+	h.mu.Unlock()
+
+	for id := range ids {
+		h.broadcastPresence(ctx, id)
+	}
+}
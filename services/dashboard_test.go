@@ -0,0 +1,42 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"shopflow/application/models"
+)
+
+func TestDashboardSessionMatchesPreviousState(t *testing.T) {
+	seven, none := uint(7), uint(0)
+	tests := []struct {
+		name  string
+		topic string
+		msg   ApplicationEventMessage
+		want  bool
+	}{
+		{name: "new status", topic: "status:in_review", msg: ApplicationEventMessage{ID: 1, Status: "in_review", PreviousStatus: "new", PreviousAssigneeID: &none}, want: true},
+		{name: "left subscribed status", topic: "status:new", msg: ApplicationEventMessage{ID: 1, Status: "in_review", PreviousStatus: "new", PreviousAssigneeID: &none}, want: true},
+		{name: "unrelated status", topic: "status:done", msg: ApplicationEventMessage{ID: 1, Status: "in_review", PreviousStatus: "new", PreviousAssigneeID: &none}},
+		{name: "reassigned away", topic: "assignee:7", msg: ApplicationEventMessage{ID: 1, Status: "new", AssigneeID: 8, PreviousStatus: "new", PreviousAssigneeID: &seven}, want: true},
+		{name: "left unassigned", topic: "unassigned", msg: ApplicationEventMessage{ID: 1, Status: "new", AssigneeID: 7, PreviousStatus: "new", PreviousAssigneeID: &none}, want: true},
+		{name: "still assigned elsewhere", topic: "unassigned", msg: ApplicationEventMessage{ID: 1, Status: "new", AssigneeID: 8, PreviousStatus: "new", PreviousAssigneeID: &seven}},
+		{name: "without previous state", topic: "status:new", msg: ApplicationEventMessage{ID: 1, Status: "in_review"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &DashboardSession{UserID: 7, topics: make(map[string]bool), viewing: make(map[uint]bool)}
+			if _, err := s.Subscribe([]string{tt.topic}); err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Matches(&models.ApplicationEvent{Data: data}); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"shopflow/application/logging"
+	"shopflow/application/models"
+	"shopflow/application/repository"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// EventStreamOptions — настройки потока событий для SSE
+type EventStreamOptions struct {
+	// Retention — сколько хранить журнал событий для продолжения по Last-Event-ID
+	Retention time.Duration
+	// BacklogLimit — максимум пропущенных событий, досылаемых при переподключении;
+	// если пропущено больше, клиенту отправляется reset и он должен перечитать список
+	BacklogLimit int
+	// SubscriberBuffer — размер очереди одного подписчика; медленный клиент,
+	// переполнивший очередь, отключается и переподключается с Last-Event-ID
+	SubscriberBuffer int
+}
+
+// DefaultEventStreamOptions — настройки по умолчанию
+func DefaultEventStreamOptions() EventStreamOptions {
+	return EventStreamOptions{
+		Retention:        24 * time.Hour,
+		BacklogLimit:     1000,
+		SubscriberBuffer: 64,
+	}
+}
+
+// EventSubscriber — локальный подписчик потока. Канал C закрывается,
+// когда подписчик отключён из-за переполнения очереди.
+type EventSubscriber struct {
+	C <-chan models.ApplicationEvent
+
+	ch     chan models.ApplicationEvent
+	userID uint
+	all    bool
+}
+
+func (sub *EventSubscriber) wants(e *models.ApplicationEvent) bool {
+	return sub.all || (e.UserID != 0 && e.UserID == sub.userID)
+}
+
+// EventStream записывает события заявок в журнал Postgres и раздаёт их
+// подписчикам этой реплики. Между репликами события расходятся через
+// LISTEN/NOTIFY: каждая реплика, включая отправителя, получает уведомление
+// и рассылает его своим подписчикам.
+type EventStream struct {
+	repo *repository.EventRepository
+	dsn  string
+	opts EventStreamOptions
+
+	mu     sync.Mutex
+	subs   map[*EventSubscriber]struct{}
+	lastID int64
+}
+
+// NewEventStream создаёт поток; dsn нужен для отдельного LISTEN-соединения
+func NewEventStream(repo *repository.EventRepository, dsn string, opts EventStreamOptions) *EventStream {
+	return &EventStream{
+		repo: repo,
+		dsn:  dsn,
+		opts: opts,
+		subs: make(map[*EventSubscriber]struct{}),
+	}
+}
+
+// HandleEvent записывает опубликованное событие в журнал.
+// Подключается к NotificationService как слушатель.
+func (s *EventStream) HandleEvent(ctx context.Context, eventType string, msg any) {
+	var userID uint
+	switch m := msg.(type) {
+	case ApplicationCreatedMessage:
+		userID = m.UserID
+	case ApplicationEventMessage:
+		userID = m.UserID
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to marshal event", "component", "stream", "event_type", eventType, logging.Err(err))
+		return
+	}
+	if _, err := s.repo.Append(ctx, eventType, userID, payload); err != nil {
+		slog.ErrorContext(ctx, "failed to append event", "component", "stream", "event_type", eventType, logging.Err(err))
+	}
+}
+
+// Subscribe подписывает клиента на события пользователя userID (all — на все события)
+func (s *EventStream) Subscribe(userID uint, all bool) *EventSubscriber {
+	ch := make(chan models.ApplicationEvent, s.opts.SubscriberBuffer)
+	sub := &EventSubscriber{C: ch, ch: ch, userID: userID, all: all}
+
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe отписывает клиента
+func (s *EventStream) Unsubscribe(sub *EventSubscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[sub]; ok {
+		delete(s.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Backlog — события после afterID, пропущенные клиентом. reset == true означает,
+// что пропущенное восстановить нельзя (журнал уже очищен или событий слишком много).
+func (s *EventStream) Backlog(ctx context.Context, afterID int64, userID uint, all bool) (events []models.ApplicationEvent, reset bool, err error) {
+	if afterID <= 0 {
+		return nil, false, nil
+	}
+	oldest, err := s.repo.OldestID(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if oldest > afterID+1 {
+		return nil, true, nil
+	}
+
+	filterUser := userID
+	if all {
+		filterUser = 0
+	}
+	events, err = s.repo.ListSince(ctx, afterID, filterUser, s.opts.BacklogLimit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(events) > s.opts.BacklogLimit {
+		return nil, true, nil
+	}
+	return events, false, nil
+}
+
+// Run слушает канал NOTIFY и рассылает события локальным подписчикам,
+// а также периодически очищает журнал. Завершается с ctx.
+func (s *EventStream) Run(ctx context.Context) {
+	listener := pq.NewListener(s.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error("listener error", "component", "stream", logging.Err(err))
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(repository.EventsChannel); err != nil {
+		slog.Error("failed to listen for events", "component", "stream", logging.Err(err))
+		return
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			if n == nil {
+				// соединение переустановлено: уведомления за время разрыва потеряны
+				s.catchUp(ctx)
+				continue
+			}
+			s.handleNotification(ctx, n.Extra)
+		case <-ping.C:
+			go listener.Ping()
+		case <-purge.C:
+			if _, err := s.repo.PurgeOlderThan(ctx, s.opts.Retention); err != nil {
+				slog.Error("failed to purge events", "component", "stream", logging.Err(err))
+			}
+		}
+	}
+}
+
+func (s *EventStream) handleNotification(ctx context.Context, payload string) {
+	var e models.ApplicationEvent
+	if err := json.Unmarshal([]byte(payload), &e); err != nil {
+		slog.Warn("invalid notification", "component", "stream", logging.Err(err))
+		return
+	}
+	if e.Data == nil {
+		// большое событие пришло без data — дочитываем из журнала
+		full, err := s.repo.GetByID(ctx, e.ID)
+		if err != nil {
+			slog.Error("failed to load event", "component", "stream", "event_id", e.ID, logging.Err(err))
+			return
+		}
+		e = *full
+	}
+	s.dispatch(&e)
+}
+
+// catchUp досылает события, записанные, пока LISTEN-соединение было разорвано
+func (s *EventStream) catchUp(ctx context.Context) {
+	s.mu.Lock()
+	lastID := s.lastID
+	s.mu.Unlock()
+	if lastID == 0 {
+		return
+	}
+
+	events, err := s.repo.ListSince(ctx, lastID, 0, s.opts.BacklogLimit)
+	if err != nil {
+		slog.Error("failed to catch up events", "component", "stream", logging.Err(err))
+		return
+	}
+	for i := range events {
+		s.dispatch(&events[i])
+	}
+}
+
+func (s *EventStream) dispatch(e *models.ApplicationEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.ID > s.lastID {
+		s.lastID = e.ID
+	}
+	for sub := range s.subs {
+		if !sub.wants(e) {
+			continue
+		}
+		select {
+		case sub.ch <- *e:
+		default:
+			// клиент не успевает читать — отключаем, он продолжит с Last-Event-ID
+			delete(s.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
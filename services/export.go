@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"shopflow/application/export"
+	"shopflow/application/logging"
+	"shopflow/application/models"
+	"shopflow/application/repository"
+)
+
+// ErrExportNotReady — задача не является выгрузкой или ещё не завершилась успешно
+var ErrExportNotReady = errors.New("export is not ready")
+
+// exportProgressEvery — как часто фоновая выгрузка сохраняет прогресс
+const exportProgressEvery = 1000
+
+// ExportApplications потоково выгружает заявки, подходящие под фильтр, в w.
+// Возвращает число выгруженных строк.
+func (s *ApplicationService) ExportApplications(ctx context.Context, filter models.ApplicationFilter, opts models.ExportOptions, w io.Writer) (int, error) {
+	return writeExport(ctx, s.repo, filter, opts, w, nil)
+}
+
+func writeExport(ctx context.Context, repo *repository.ApplicationRepository, filter models.ApplicationFilter, opts models.ExportOptions, w io.Writer, progress JobProgress) (int, error) {
+	formatter, err := export.NewFormatter(opts)
+	if err != nil {
+		return 0, err
+	}
+	writer, err := export.NewRowWriter(opts.Format, w)
+	if err != nil {
+		return 0, err
+	}
+	if err := writer.WriteHeader(formatter.Columns()); err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	err = repo.StreamByFilter(ctx, filter, func(app *models.Application) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := writer.WriteRow(formatter.Values(app)); err != nil {
+			return err
+		}
+		rows++
+		if progress != nil && rows%exportProgressEvery == 0 {
+			return progress(rows)
+		}
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, writer.Close()
+}
+
+// exportChunkSize — размер части файла фоновой выгрузки в базе
+const exportChunkSize = 1 << 20
+
+// StartExport запускает фоновую выгрузку. Файл сохраняется в базе, поэтому его может
+// отдать любая реплика (WriteExportFile).
+func (s *JobService) StartExport(ctx context.Context, userID uint, filter models.ApplicationFilter, opts models.ExportOptions) (*models.Job, error) {
+	if err := export.Validate(opts); err != nil {
+		return nil, err
+	}
+	total, err := s.apps.CountByFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	maxID, err := s.apps.MaxID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter.MaxID = maxID
+
+	params := models.ExportJobParams{Filter: filter, Options: opts}
+	return s.Start(ctx, models.JobTypeExport, userID, params, func(ctx context.Context, job *models.Job, progress JobProgress) (any, error) {
+		if err := s.jobs.MarkRunning(ctx, job.ID, total); err != nil {
+			return nil, err
+		}
+		return s.runExport(ctx, job, params, progress)
+	})
+}
+
+// chunkWriter пишет поток в export_chunks частями по exportChunkSize
+type chunkWriter struct {
+	ctx   context.Context
+	jobs  *repository.JobRepository
+	jobID uint
+	buf   []byte
+	seq   int
+	size  int64
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		room := exportChunkSize - len(w.buf)
+		if room > len(p) {
+			room = len(p)
+		}
+		w.buf = append(w.buf, p[:room]...)
+		p = p[room:]
+		if len(w.buf) == exportChunkSize {
+			if err := w.Flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	w.size += int64(n)
+	return n, nil
+}
+
+// Flush сохраняет накопленную часть
+func (w *chunkWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	if err := w.jobs.SaveExportChunk(w.ctx, w.jobID, w.seq, w.buf); err != nil {
+		return err
+	}
+	w.seq++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+func (s *JobService) runExport(ctx context.Context, job *models.Job, params models.ExportJobParams, progress JobProgress) (_ any, err error) {
+	defer func() {
+		if err != nil {
+			if delErr := s.jobs.DeleteExportChunks(context.WithoutCancel(ctx), job.ID); delErr != nil {
+				slog.ErrorContext(ctx, "failed to delete export file", "job_id", job.ID, logging.Err(delErr))
+			}
+		}
+	}()
+
+	w := &chunkWriter{ctx: ctx, jobs: s.jobs, jobID: job.ID, buf: make([]byte, 0, exportChunkSize)}
+	rows, err := writeExport(ctx, s.apps, params.Filter, params.Options, w, progress)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	if err := progress(rows); err != nil {
+		return nil, err
+	}
+
+	return models.ExportJobResult{
+		Rows:        rows,
+		FileName:    fmt.Sprintf("applications-export-%d.%s", job.ID, params.Options.Format),
+		ContentType: export.ContentType(params.Options.Format),
+		Size:        w.size,
+	}, nil
+}
+
+// ExportResult — метаданные файла завершённой фоновой выгрузки
+func (s *JobService) ExportResult(job *models.Job) (models.ExportJobResult, error) {
+	var result models.ExportJobResult
+	if job.Type != models.JobTypeExport || job.Status != models.JobStatusSucceeded {
+		return result, ErrExportNotReady
+	}
+	if err := json.Unmarshal(job.Result, &result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// WriteExportFile потоково пишет файл выгрузки в w, читая из базы по одной части
+func (s *JobService) WriteExportFile(ctx context.Context, job *models.Job, w io.Writer) error {
+	for seq := 0; ; seq++ {
+		data, err := s.jobs.ExportChunk(ctx, job.ID, seq)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"shopflow/application/importer"
+	"shopflow/application/logging"
+	"shopflow/application/models"
+	"shopflow/application/publisher"
+	"shopflow/application/repository"
+	"time"
+)
+
+// maxReportedImportErrors — сколько ошибок строк возвращать в отчёте
+const maxReportedImportErrors = 1000
+
+// ImportApplications загружает заявки из CSV/NDJSON. Файл читается потоково,
+// валидные строки отправляются в БД через COPY. Если в файле есть ошибки и
+// SkipInvalid не задан, ничего не записывается — отчёт содержит ошибки по строкам.
+func (s *ApplicationService) ImportApplications(ctx context.Context, r io.Reader, opts models.ImportOptions) (models.ImportReport, error) {
+	report := models.ImportReport{DryRun: opts.DryRun, Errors: []models.ImportLineError{}}
+
+	reader, err := importer.NewReader(opts.Format, r)
+	if err != nil {
+		return report, err
+	}
+
+	var imp *repository.ApplicationImporter
+	if !opts.DryRun {
+		if imp, err = s.repo.BeginImport(ctx); err != nil {
+			return report, err
+		}
+		defer imp.Rollback()
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+
+		report.TotalRows++
+		if row.Err != nil {
+			report.Failed++
+			if len(report.Errors) < maxReportedImportErrors {
+				report.Errors = append(report.Errors, models.ImportLineError{Line: row.Line, Error: row.Err.Error()})
+			} else {
+				report.ErrorsTruncated = true
+			}
+			continue
+		}
+		report.Valid++
+
+		app := row.App
+		if !opts.PreserveTimestamps {
+			app.CreatedAt, app.UpdatedAt = time.Time{}, time.Time{}
+		}
+		if !opts.PreserveStatus {
+			app.Status = ""
+		}
+		if imp != nil {
+			if err := imp.Add(ctx, app); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if opts.DryRun || (report.Failed > 0 && !opts.SkipInvalid) {
+		return report, nil
+	}
+
+	var created []publisher.ApplicationCreatedMessage
+	report.Imported, err = imp.Commit(ctx, func(app *models.Application) {
+		if !opts.SuppressEvents {
+			created = append(created, publisher.ApplicationCreatedMessage{
+				ID:     app.ID,
+				UserID: app.UserID,
+				Text:   app.Text,
+				File:   app.FileURL,
+			})
+		}
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// События публикуем только после фиксации транзакции
+	if s.publisher != nil {
+		for _, msg := range created {
+			if err := s.publisher.PublishApplicationCreated(ctx, msg); err != nil {
+				slog.ErrorContext(ctx, "failed to publish application_created for imported application", "application_id", msg.ID, logging.Err(err))
+			}
+		}
+	}
+	return report, nil
+}
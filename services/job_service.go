@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"shopflow/application/logging"
+	"shopflow/application/models"
+	"shopflow/application/repository"
+	"sync"
+	"time"
+)
+
+// ErrJobCancelled — задача остановлена по запросу отмены
+var ErrJobCancelled = errors.New("job cancelled")
+
+// DefaultJobChunkSize — сколько заявок фоновая задача обрабатывает за один шаг
+const DefaultJobChunkSize = 500
+
+// bulkStatusSampleSize — сколько ID показывать в ответе dry-run
+const bulkStatusSampleSize = 20
+
+// Заявки, заблокированные другими транзакциями, массовая смена статуса пропускает
+// и пробует снова: до bulkLockedRetries раз подряд без продвижения с паузой bulkLockedRetryDelay.
+const (
+	bulkLockedRetries    = 5
+	bulkLockedRetryDelay = 200 * time.Millisecond
+)
+
+// Выполняющиеся задачи продлеваются каждые jobHeartbeatInterval; задачи, не продлевавшиеся
+// дольше jobStaleAfter, считаются прерванными (реплика упала) и завершаются со статусом failed.
+const (
+	jobHeartbeatInterval = 30 * time.Second
+	jobStaleAfter        = 2 * time.Minute
+)
+
+// jobPurgeInterval — как часто удалять устаревшие выгрузки
+const jobPurgeInterval = time.Hour
+
+// JobOptions — настройки фоновых задач
+type JobOptions struct {
+	// ExportRetention — сколько хранить завершённую выгрузку и её файл
+	ExportRetention time.Duration
+}
+
+// DefaultJobOptions — настройки по умолчанию
+func DefaultJobOptions() JobOptions {
+	return JobOptions{ExportRetention: 24 * time.Hour}
+}
+
+// JobProgress — обратная связь выполняющейся задачи: сохраняет прогресс
+// и возвращает ErrJobCancelled, если задачу попросили остановить.
+type JobProgress func(processed int) error
+
+// JobFunc — тело фоновой задачи. Возвращаемый результат сохраняется в jobs.result.
+type JobFunc func(ctx context.Context, job *models.Job, progress JobProgress) (any, error)
+
+// JobService запускает фоновые задачи и отслеживает их состояние в таблице jobs
+type JobService struct {
+	jobs      *repository.JobRepository
+	apps      *repository.ApplicationRepository
+	publisher *NotificationService
+	opts      JobOptions
+	chunkSize int
+
+	mu      sync.Mutex
+	cancels map[uint]context.CancelFunc
+	running sync.WaitGroup
+}
+
+// NewJobService создаёт сервис фоновых задач
+func NewJobService(jobs *repository.JobRepository, apps *repository.ApplicationRepository, publisher *NotificationService, opts JobOptions) *JobService {
+	return &JobService{
+		jobs:      jobs,
+		apps:      apps,
+		publisher: publisher,
+		opts:      opts,
+		chunkSize: DefaultJobChunkSize,
+		cancels:   make(map[uint]context.CancelFunc),
+	}
+}
+
+func (s *JobService) GetJob(ctx context.Context, id uint) (*models.Job, error) {
+	return s.jobs.GetByID(ctx, id)
+}
+
+// CancelJob помечает задачу на отмену. Флаг хранится в БД, поэтому задачу,
+// выполняющуюся на другой реплике, тоже можно остановить; локальная задача
+// прерывается сразу.
+func (s *JobService) CancelJob(ctx context.Context, id uint) (*models.Job, error) {
+	job, err := s.jobs.RequestCancel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if cancel, ok := s.cancels[id]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+	return job, nil
+}
+
+// Start создаёт задачу и выполняет fn в отдельной горутине. Задача не зависит
+// от отмены ctx (запрос завершится раньше неё), но наследует его поля для логов и трассировки.
+func (s *JobService) Start(ctx context.Context, jobType string, userID uint, params any, fn JobFunc) (*models.Job, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	job := &models.Job{Type: jobType, Params: raw, CreatedBy: userID}
+	if err := s.jobs.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.mu.Lock()
+	s.cancels[job.ID] = cancel
+	s.mu.Unlock()
+
+	s.running.Add(1)
+	go func(job models.Job) {
+		defer s.running.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.cancels, job.ID)
+			s.mu.Unlock()
+			cancel()
+		}()
+		s.run(ctx, &job, fn)
+	}(*job)
+
+	return job, nil
+}
+
+// Run продлевает задачи этой реплики, завершает задачи упавших реплик
+// (в том числе сразу при запуске) и удаляет выгрузки старше ExportRetention. Завершается с ctx.
+func (s *JobService) Run(ctx context.Context) {
+	heartbeat := time.NewTicker(jobHeartbeatInterval)
+	defer heartbeat.Stop()
+	purge := time.NewTicker(jobPurgeInterval)
+	defer purge.Stop()
+
+	s.heartbeat(ctx)
+	s.purgeExports(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			s.heartbeat(ctx)
+		case <-purge.C:
+			s.purgeExports(ctx)
+		}
+	}
+}
+
+func (s *JobService) heartbeat(ctx context.Context) {
+	if err := s.jobs.Touch(ctx, s.localJobIDs()); err != nil {
+		slog.ErrorContext(ctx, "failed to touch running jobs", logging.Err(err))
+	}
+	if n, err := s.jobs.FailStale(ctx, jobStaleAfter); err != nil {
+		slog.ErrorContext(ctx, "failed to fail stale jobs", logging.Err(err))
+	} else if n > 0 {
+		slog.WarnContext(ctx, "stale jobs marked as failed", "count", n)
+	}
+}
+
+// purgeExports удаляет устаревшие выгрузки; несколько реплик могут делать это одновременно
+func (s *JobService) purgeExports(ctx context.Context) {
+	if s.opts.ExportRetention <= 0 {
+		return
+	}
+	if n, err := s.jobs.PurgeFinishedExports(ctx, s.opts.ExportRetention); err != nil {
+		slog.ErrorContext(ctx, "failed to purge finished exports", logging.Err(err))
+	} else if n > 0 {
+		slog.InfoContext(ctx, "finished exports purged", "count", n)
+	}
+}
+
+func (s *JobService) localJobIDs() []uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]uint, 0, len(s.cancels))
+	for id := range s.cancels {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Shutdown прерывает задачи, выполняющиеся на этой реплике, и ждёт, пока они
+// сохранят итоговое состояние (задачи завершаются со статусом cancelled)
+func (s *JobService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *JobService) run(ctx context.Context, job *models.Job, fn JobFunc) {
+	progress := func(processed int) error {
+		cancelRequested, err := s.jobs.UpdateProgress(ctx, job.ID, processed)
+		if err != nil {
+			return err
+		}
+		if cancelRequested || ctx.Err() != nil {
+			return ErrJobCancelled
+		}
+		return nil
+	}
+
+	result, err := fn(ctx, job, progress)
+
+	status := models.JobStatusSucceeded
+	switch {
+	case errors.Is(err, ErrJobCancelled), errors.Is(err, context.Canceled):
+		status, err = models.JobStatusCancelled, nil
+	case err != nil:
+		status = models.JobStatusFailed
+		slog.ErrorContext(ctx, "job failed", "job_id", job.ID, "job_type", job.Type, logging.Err(err))
+	}
+	// итог сохраняем и для отменённой задачи
+	if err := s.jobs.Finish(context.WithoutCancel(ctx), job.ID, status, result, err); err != nil {
+		slog.ErrorContext(ctx, "failed to finish job", "job_id", job.ID, logging.Err(err))
+	}
+}
+
+// PreviewBulkStatusChange — dry-run массовой смены статуса: число заявок и примеры ID
+func (s *JobService) PreviewBulkStatusChange(ctx context.Context, filter models.ApplicationFilter, targetStatus string) (models.BulkStatusChangePreview, error) {
+	filter.ExcludeStatus = targetStatus
+
+	count, err := s.apps.CountByFilter(ctx, filter)
+	if err != nil {
+		return models.BulkStatusChangePreview{}, err
+	}
+	ids, err := s.apps.SampleIDsByFilter(ctx, filter, bulkStatusSampleSize)
+	if err != nil {
+		return models.BulkStatusChangePreview{}, err
+	}
+	return models.BulkStatusChangePreview{DryRun: true, Count: count, SampleIDs: ids}, nil
+}
+
+// StartBulkStatusChange запускает фоновую задачу смены статуса всех заявок, подходящих под фильтр.
+// Выборка фиксируется на момент запуска: заявки, созданные позже, не затрагиваются.
+func (s *JobService) StartBulkStatusChange(ctx context.Context, userID uint, filter models.ApplicationFilter, targetStatus string) (*models.Job, error) {
+	maxID, err := s.apps.MaxID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter.MaxID = maxID
+	filter.ExcludeStatus = targetStatus
+
+	params := models.BulkStatusChangeParams{Filter: filter, TargetStatus: targetStatus}
+	return s.Start(ctx, models.JobTypeBulkStatusChange, userID, params, func(ctx context.Context, job *models.Job, progress JobProgress) (any, error) {
+		return s.runBulkStatusChange(ctx, job, params, progress)
+	})
+}
+
+func (s *JobService) runBulkStatusChange(ctx context.Context, job *models.Job, params models.BulkStatusChangeParams, progress JobProgress) (any, error) {
+	total, err := s.apps.CountByFilter(ctx, params.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.jobs.MarkRunning(ctx, job.ID, total); err != nil {
+		return nil, err
+	}
+
+	updated, skipped := 0, 0
+	result := func() map[string]int { return map[string]int{"updated": updated, "skipped": skipped} }
+	idle := 0
+	for {
+		if ctx.Err() != nil {
+			return result(), ErrJobCancelled
+		}
+
+		apps, err := s.apps.SetStatusByFilter(ctx, params.Filter, params.TargetStatus, s.chunkSize)
+		if err != nil {
+			return result(), err
+		}
+		for i := range apps {
+			s.publishStatusChanged(ctx, &apps[i])
+		}
+		updated += len(apps)
+
+		if err := progress(updated); err != nil {
+			return result(), err
+		}
+		if len(apps) == s.chunkSize {
+			idle = 0
+			continue
+		}
+
+		// неполная порция: подходящих заявок не осталось или остальные сейчас заблокированы
+		remaining, err := s.apps.CountByFilter(ctx, params.Filter)
+		if err != nil {
+			return result(), err
+		}
+		if remaining == 0 {
+			return result(), nil
+		}
+		if len(apps) > 0 {
+			idle = 0
+		} else if idle++; idle >= bulkLockedRetries {
+			skipped = remaining
+			slog.WarnContext(ctx, "bulk status change skipped locked applications",
+				"job_id", job.ID, "skipped", skipped)
+			return result(), nil
+		}
+		select {
+		case <-ctx.Done():
+			return result(), ErrJobCancelled
+		case <-time.After(bulkLockedRetryDelay):
+		}
+	}
+}
+
+func (s *JobService) publishStatusChanged(ctx context.Context, app *models.Application) {
+	if s.publisher == nil {
+		return
+	}
+	msg := ApplicationEventMessage{
+		ID:      app.ID,
+		UserID:  app.UserID,
+		Status:  app.Status,
+		Version: app.Version,
+	}
+	if app.AssigneeID != nil {
+		msg.AssigneeID = *app.AssigneeID
+	}
+	msg.SetPrevious(app)
+	err := s.publisher.PublishApplicationEvent(ctx, RoutingKeyApplicationStatusChanged, msg)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to publish status change", "application_id", app.ID, logging.Err(err))
+	}
+}
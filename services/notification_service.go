@@ -1,15 +1,71 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
+	"shopflow/application/logging"
+	"shopflow/application/metrics"
+	"shopflow/application/models"
+	"shopflow/application/tracing"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type NotificationService struct {
 	MQConn *amqp.Connection
+	// PublishTimeout ограничивает отправку одного события в RabbitMQ (0 — без ограничения)
+	PublishTimeout time.Duration
+
+	listeners []EventListener
+	pending   sync.WaitGroup
+}
+
+// EventListener получает каждое публикуемое событие (например, для доставки вебхуков).
+// Вызывается синхронно до отправки в RabbitMQ, даже если соединения нет;
+// ctx — контекст публикации (запроса или фоновой задачи).
+type EventListener func(ctx context.Context, routingKey string, msg any)
+
+// AddListener подписывает слушателя на все публикуемые события.
+// Слушателей нужно регистрировать до начала обработки запросов.
+func (s *NotificationService) AddListener(l EventListener) {
+	s.listeners = append(s.listeners, l)
+}
+
+// Go выполняет публикацию в фоне, не задерживая ответ клиенту.
+// Такие публикации дожидаются при остановке сервиса (Flush).
+func (s *NotificationService) Go(publish func()) {
+	if s == nil {
+		go publish()
+		return
+	}
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		publish()
+	}()
+}
+
+// Flush ждёт завершения фоновых публикаций, запущенных через Go
+func (s *NotificationService) Flush(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 type ApplicationCreatedMessage struct {
@@ -17,7 +73,10 @@ type ApplicationCreatedMessage struct {
 	UserID uint   `json:"user_id"`
 	Text   string `json:"text"`
 	File   string `json:"file_url"`
+	Status string `json:"status,omitempty"`
 	Email  string `json:"email"`
+	// RequestID — X-Request-ID запроса, породившего событие
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func NewEventPublisher(conn *amqp.Connection) *NotificationService {
@@ -26,42 +85,125 @@ func NewEventPublisher(conn *amqp.Connection) *NotificationService {
 
 var ErrNoMQConnection = errors.New("no rabbitmq connection")
 
+// ApplicationEventMessage — событие об изменении заявки (обновление, смена статуса, удаление)
+type ApplicationEventMessage struct {
+	ID      uint   `json:"id"`
+	UserID  uint   `json:"user_id,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Email   string `json:"email,omitempty"`
+	// AssigneeID — назначенный сотрудник (0 — не назначена)
+	AssigneeID uint `json:"assignee_id,omitempty"`
+	// PreviousStatus и PreviousAssigneeID — значения до изменения (в событиях обновления
+	// и смены статуса), чтобы подписчики на старый статус или сотрудника узнали, что заявка ушла
+	PreviousStatus     string `json:"previous_status,omitempty"`
+	PreviousAssigneeID *uint  `json:"previous_assignee_id,omitempty"`
+	// RequestID — X-Request-ID запроса, породившего событие
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// SetPrevious заполняет статус и назначение заявки до изменения, если они известны
+// (0 в PreviousAssigneeID — заявка не была назначена)
+func (m *ApplicationEventMessage) SetPrevious(app *models.Application) {
+	if app.PreviousStatus == "" {
+		return
+	}
+	var assignee uint
+	if app.PreviousAssigneeID != nil {
+		assignee = *app.PreviousAssigneeID
+	}
+	m.PreviousStatus = app.PreviousStatus
+	m.PreviousAssigneeID = &assignee
+}
+
+// Ключи маршрутизации событий заявок
+const (
+	RoutingKeyApplicationCreated       = "application_created"
+	RoutingKeyApplicationUpdated       = "application_updated"
+	RoutingKeyApplicationStatusChanged = "application_status_changed"
+	RoutingKeyApplicationDeleted       = "application_deleted"
+)
+
 // PublishApplicationCreated публикует событие о созданной заявке в очередь "application_created"
-func (s *NotificationService) PublishApplicationCreated(msg ApplicationCreatedMessage) error {
-	if s == nil || s.MQConn == nil {
+func (s *NotificationService) PublishApplicationCreated(ctx context.Context, msg ApplicationCreatedMessage) error {
+	msg.RequestID = logging.RequestID(ctx)
+	return s.publish(ctx, RoutingKeyApplicationCreated, msg, msg.ID, msg.UserID)
+}
+
+// PublishApplicationEvent публикует событие об изменении заявки с указанным ключом маршрутизации
+func (s *NotificationService) PublishApplicationEvent(ctx context.Context, routingKey string, msg ApplicationEventMessage) error {
+	msg.RequestID = logging.RequestID(ctx)
+	return s.publish(ctx, routingKey, msg, msg.ID, msg.UserID)
+}
+
+func (s *NotificationService) publish(ctx context.Context, routingKey string, msg any, id, userID uint) (err error) {
+	if s == nil {
+		return ErrNoMQConnection
+	}
+	for _, l := range s.listeners {
+		l(ctx, routingKey, msg)
+	}
+	defer func() { metrics.Published(routingKey, err) }()
+
+	exchangeName := "shopflow.events" // или os.Getenv("EXCHANGE_NAME")
+	ctx, span := tracing.StartPublish(ctx, exchangeName, routingKey)
+	defer func() { tracing.End(span, err) }()
+
+	if s.MQConn == nil {
 		return ErrNoMQConnection
 	}
 
-	ch, err := s.MQConn.Channel()
+	body, err := json.Marshal(msg)
 	if err != nil {
-		log.Println("[notification] failed to open channel:", err)
+		slog.ErrorContext(ctx, "failed to marshal message", "component", "notification", "routing_key", routingKey, logging.Err(err))
 		return err
 	}
-	defer ch.Close()
 
-	body, err := json.Marshal(msg)
+	if s.PublishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.PublishTimeout)
+		defer cancel()
+	}
+
+	// amqp091 не учитывает контекст при публикации, поэтому ждём отправку не дольше ctx
+	done := make(chan error, 1)
+	go func() {
+		done <- s.send(ctx, exchangeName, routingKey, body)
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
 	if err != nil {
-		log.Println("[notification] failed to marshal message:", err)
+		slog.ErrorContext(ctx, "failed to publish message", "component", "notification", "routing_key", routingKey, logging.Err(err))
 		return err
 	}
 
-	exchangeName := "shopflow.events" // или os.Getenv("EXCHANGE_NAME")
-	routingKey := "application_created"
+	slog.InfoContext(ctx, "event published", "component", "notification",
+		"routing_key", routingKey, "application_id", id, "owner_id", userID)
+	return nil
+}
+
+// send открывает канал и отправляет сообщение в exchange
+func (s *NotificationService) send(ctx context.Context, exchange, routingKey string, body []byte) error {
+	ch, err := s.MQConn.Channel()
+	if err != nil {
+		return fmt.Errorf("open channel: %w", err)
+	}
+	defer ch.Close()
 
-	if err := ch.Publish(
-		exchangeName,
+	return ch.PublishWithContext(
+		ctx,
+		exchange,
 		routingKey,
 		false,
 		false,
 		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
+			ContentType:   "application/json",
+			CorrelationId: logging.RequestID(ctx),
+			Headers:       tracing.InjectAMQP(ctx, nil),
+			Body:          body,
 		},
-	); err != nil {
-		log.Println("[notification] failed to publish message:", err)
-		return err
-	}
-
-	log.Printf("[notification] Published %s id=%d user_id=%d\n", routingKey, msg.ID, msg.UserID)
-	return nil
+	)
 }
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"shopflow/application/logging"
+	"shopflow/application/models"
+	"shopflow/application/repository"
+	"shopflow/application/tracing"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Ключи маршрутизации событий Auth об отзыве токенов
+const (
+	RoutingKeyTokenRevoked            = "auth.token_revoked"
+	RoutingKeyUserLoggedOutEverywhere = "auth.user_logged_out_everywhere"
+)
+
+// TokenRevokedMessage — Auth отозвал один токен (выход, компрометация)
+type TokenRevokedMessage struct {
+	JTI    string `json:"jti"`
+	UserID uint   `json:"user_id"`
+	// ExpiresAt — exp отозванного токена; без него отзыв хранится Retention
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UserLoggedOutEverywhereMessage — пользователь вышел на всех устройствах:
+// недействительны все его токены, выданные до LoggedOutAt
+type UserLoggedOutEverywhereMessage struct {
+	UserID      uint      `json:"user_id"`
+	LoggedOutAt time.Time `json:"logged_out_at"`
+}
+
+// errBadRevocationEvent — событие нельзя разобрать; повторная доставка не поможет
+var errBadRevocationEvent = errors.New("malformed revocation event")
+
+// RevocationOptions — настройки отзыва токенов
+type RevocationOptions struct {
+	// Exchange — topic exchange, в который Auth публикует события
+	Exchange string
+	// Retention — сколько действует отзыв без известного exp и отсечка по времени выхода;
+	// должно быть не меньше срока жизни токена
+	Retention time.Duration
+	// PurgeInterval — как часто удалять истёкшие отзывы
+	PurgeInterval time.Duration
+	// ReconnectDelay — пауза перед повторной подпиской после разрыва канала
+	ReconnectDelay time.Duration
+}
+
+// DefaultRevocationOptions — настройки по умолчанию
+func DefaultRevocationOptions() RevocationOptions {
+	return RevocationOptions{
+		Exchange:       "shopflow.events",
+		Retention:      24 * time.Hour,
+		PurgeInterval:  10 * time.Minute,
+		ReconnectDelay: 5 * time.Second,
+	}
+}
+
+// RevocationService — отозванные токены. Проверка (IsRevoked) идёт только по памяти;
+// события Auth сохраняются в Postgres, чтобы отзывы пережили перезапуск, и применяются
+// к памяти сразу. Каждая реплика читает события из своей очереди, поэтому знает обо всех.
+type RevocationService struct {
+	repo *repository.RevocationRepository
+	conn *amqp.Connection
+	opts RevocationOptions
+
+	mu      sync.RWMutex
+	tokens  map[string]time.Time // jti -> exp
+	cutoffs map[uint]time.Time   // user_id -> токены, выданные раньше, недействительны
+}
+
+func NewRevocationService(repo *repository.RevocationRepository, conn *amqp.Connection, opts RevocationOptions) *RevocationService {
+	return &RevocationService{
+		repo:    repo,
+		conn:    conn,
+		opts:    opts,
+		tokens:  make(map[string]time.Time),
+		cutoffs: make(map[uint]time.Time),
+	}
+}
+
+// IsRevoked — отозван ли токен пользователя userID с claims jti и iat.
+// Токен без iat при действующей отсечке считается отозванным: доказать, что он выдан позже, нечем.
+func (s *RevocationService) IsRevoked(userID uint, jti string, issuedAt time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if jti != "" {
+		if _, ok := s.tokens[jti]; ok {
+			return true
+		}
+	}
+	if cutoff, ok := s.cutoffs[userID]; ok {
+		return issuedAt.IsZero() || issuedAt.Before(cutoff)
+	}
+	return false
+}
+
+// Load дополняет память отзывами из базы (в том числе полученными другими репликами)
+func (s *RevocationService) Load(ctx context.Context) error {
+	tokens, cutoffs, err := s.repo.Active(ctx, s.opts.Retention)
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		s.revokeToken(t)
+	}
+	for _, c := range cutoffs {
+		s.revokeUserTokens(c)
+	}
+	slog.DebugContext(ctx, "token revocations loaded", "component", "revocation",
+		"tokens", len(tokens), "users", len(cutoffs))
+	return nil
+}
+
+func (s *RevocationService) revokeToken(t models.RevokedToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.tokens[t.JTI]; !ok || t.ExpiresAt.After(prev) {
+		s.tokens[t.JTI] = t.ExpiresAt
+	}
+}
+
+func (s *RevocationService) revokeUserTokens(c models.UserTokenCutoff) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.cutoffs[c.UserID]; !ok || c.IssuedBefore.After(prev) {
+		s.cutoffs[c.UserID] = c.IssuedBefore
+	}
+}
+
+// HandleEvent применяет событие Auth: сначала к памяти, затем сохраняет в базе.
+// Ошибку сохранения стоит повторить — применение идемпотентно.
+func (s *RevocationService) HandleEvent(ctx context.Context, routingKey string, body []byte) error {
+	switch routingKey {
+	case RoutingKeyTokenRevoked:
+		var msg TokenRevokedMessage
+		if err := json.Unmarshal(body, &msg); err != nil || msg.JTI == "" {
+			return errBadRevocationEvent
+		}
+		t := models.RevokedToken{JTI: msg.JTI, UserID: msg.UserID, ExpiresAt: msg.ExpiresAt}
+		if t.ExpiresAt.IsZero() {
+			t.ExpiresAt = time.Now().Add(s.opts.Retention)
+		}
+		if t.ExpiresAt.Before(time.Now()) {
+			return nil // токен уже истёк сам
+		}
+		s.revokeToken(t)
+		slog.InfoContext(ctx, "token revoked", "component", "revocation", "owner_id", t.UserID, "jti", t.JTI)
+		return s.repo.RevokeToken(ctx, t)
+
+	case RoutingKeyUserLoggedOutEverywhere:
+		var msg UserLoggedOutEverywhereMessage
+		if err := json.Unmarshal(body, &msg); err != nil || msg.UserID == 0 {
+			return errBadRevocationEvent
+		}
+		c := models.UserTokenCutoff{UserID: msg.UserID, IssuedBefore: msg.LoggedOutAt}
+		if c.IssuedBefore.IsZero() {
+			c.IssuedBefore = time.Now()
+		}
+		s.revokeUserTokens(c)
+		slog.InfoContext(ctx, "user tokens revoked", "component", "revocation",
+			"owner_id", c.UserID, "issued_before", c.IssuedBefore)
+		return s.repo.RevokeUserTokens(ctx, c)
+	}
+	return fmt.Errorf("%w: unexpected routing key %q", errBadRevocationEvent, routingKey)
+}
+
+// Run читает события Auth и периодически удаляет истёкшие отзывы.
+// После разрыва канала подписывается заново. Завершается с ctx.
+func (s *RevocationService) Run(ctx context.Context) {
+	purge := time.NewTicker(s.opts.PurgeInterval)
+	defer purge.Stop()
+	for {
+		err := s.consume(ctx, purge.C)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.ErrorContext(ctx, "revocation consumer stopped, resubscribing", "component", "revocation", logging.Err(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.opts.ReconnectDelay):
+		}
+	}
+}
+
+func (s *RevocationService) consume(ctx context.Context, purge <-chan time.Time) error {
+	if s.conn == nil {
+		return ErrNoMQConnection
+	}
+	ch, err := s.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("open channel: %w", err)
+	}
+	defer ch.Close()
+
+	if err := ch.ExchangeDeclare(s.opts.Exchange, "topic", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare exchange: %w", err)
+	}
+	// своя временная очередь у каждой реплики: событие должна получить каждая
+	q, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return fmt.Errorf("declare queue: %w", err)
+	}
+	for _, key := range []string{RoutingKeyTokenRevoked, RoutingKeyUserLoggedOutEverywhere} {
+		if err := ch.QueueBind(q.Name, key, s.opts.Exchange, false, nil); err != nil {
+			return fmt.Errorf("bind queue: %w", err)
+		}
+	}
+	// события, пришедшие пока очереди не было, другие реплики уже сохранили в базе
+	if err := s.Load(ctx); err != nil {
+		return fmt.Errorf("load revocations: %w", err)
+	}
+
+	deliveries, err := ch.Consume(q.Name, "", false, true, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("consume: %w", err)
+	}
+	slog.InfoContext(ctx, "listening for token revocations", "component", "revocation", "exchange", s.opts.Exchange)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case d, ok := <-deliveries:
+			if !ok {
+				return errors.New("delivery channel closed")
+			}
+			s.handleDelivery(ctx, d)
+		case <-purge:
+			s.purge(ctx)
+		}
+	}
+}
+
+func (s *RevocationService) handleDelivery(ctx context.Context, d amqp.Delivery) {
+	ctx, span := tracing.StartConsume(tracing.ExtractAMQP(ctx, d.Headers), d.Exchange, d.RoutingKey)
+	err := s.HandleEvent(ctx, d.RoutingKey, d.Body)
+	tracing.End(span, err)
+
+	switch {
+	case err == nil:
+		_ = d.Ack(false)
+	case errors.Is(err, errBadRevocationEvent):
+		slog.WarnContext(ctx, "dropping revocation event", "component", "revocation",
+			"routing_key", d.RoutingKey, logging.Err(err))
+		_ = d.Ack(false)
+	default:
+		// в памяти отзыв уже действует; вернём событие в очередь, чтобы сохранить его позже
+		slog.ErrorContext(ctx, "failed to save revocation", "component", "revocation",
+			"routing_key", d.RoutingKey, logging.Err(err))
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+		_ = d.Nack(false, true)
+	}
+}
+
+// purge удаляет истёкшие отзывы из памяти и базы
+func (s *RevocationService) purge(ctx context.Context) {
+	now := time.Now()
+	s.mu.Lock()
+	for jti, exp := range s.tokens {
+		if exp.Before(now) {
+			delete(s.tokens, jti)
+		}
+	}
+	for userID, cutoff := range s.cutoffs {
+		if cutoff.Before(now.Add(-s.opts.Retention)) {
+			delete(s.cutoffs, userID)
+		}
+	}
+	s.mu.Unlock()
+
+	if err := s.repo.PurgeExpired(ctx, s.opts.Retention); err != nil {
+		slog.ErrorContext(ctx, "failed to purge revocations", "component", "revocation", logging.Err(err))
+	}
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	mrand "math/rand/v2"
+	"net/http"
+	"shopflow/application/logging"
+	"shopflow/application/models"
+	"shopflow/application/repository"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidWebhook — некорректные параметры подписки
+var ErrInvalidWebhook = errors.New("invalid webhook subscription")
+
+// Заголовки исходящих вебхуков. Подпись: HMAC-SHA256(secret, "<timestamp>.<body>") в hex,
+// передаётся как "t=<timestamp>,v1=<hex>"; получатель должен проверять и подпись, и свежесть timestamp.
+const (
+	WebhookHeaderEvent     = "X-ShopFlow-Event"
+	WebhookHeaderDelivery  = "X-ShopFlow-Delivery"
+	WebhookHeaderTimestamp = "X-ShopFlow-Timestamp"
+	WebhookHeaderSignature = "X-ShopFlow-Signature"
+)
+
+// webhookEventTypes — события, на которые можно подписаться
+var webhookEventTypes = map[string]bool{
+	models.WebhookEventAll:             true,
+	RoutingKeyApplicationCreated:       true,
+	RoutingKeyApplicationUpdated:       true,
+	RoutingKeyApplicationStatusChanged: true,
+	RoutingKeyApplicationDeleted:       true,
+}
+
+// maxWebhookResponseLog — сколько байт ответа подписчика сохранять в журнале
+const maxWebhookResponseLog = 1024
+
+// WebhookOptions — настройки доставки вебхуков
+type WebhookOptions struct {
+	// MaxAttempts — число попыток доставки одного события
+	MaxAttempts int
+	// DisableAfter — после стольких ошибок подряд подписка отключается (0 — никогда)
+	DisableAfter int
+	// Timeout — таймаут одного HTTP-запроса
+	Timeout time.Duration
+	// BaseBackoff и MaxBackoff — границы экспоненциальной задержки между попытками
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// PollInterval — как часто воркер ищет доставки, время которых пришло
+	PollInterval time.Duration
+	// Concurrency — сколько доставок отправляется одновременно
+	Concurrency int
+	// Retention — сколько хранить журнал завершённых доставок
+	Retention time.Duration
+}
+
+// DefaultWebhookOptions — настройки по умолчанию
+func DefaultWebhookOptions() WebhookOptions {
+	return WebhookOptions{
+		MaxAttempts:  8,
+		DisableAfter: 20,
+		Timeout:      10 * time.Second,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+		PollInterval: 2 * time.Second,
+		Concurrency:  8,
+		Retention:    7 * 24 * time.Hour,
+	}
+}
+
+// WebhookService управляет подписками и доставляет события подписчикам по HTTP
+type WebhookService struct {
+	repo   *repository.WebhookRepository
+	client *http.Client
+	opts   WebhookOptions
+}
+
+func NewWebhookService(repo *repository.WebhookRepository, opts WebhookOptions) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		client: &http.Client{Timeout: opts.Timeout},
+		opts:   opts,
+	}
+}
+
+func validateWebhookEventTypes(types []string) error {
+	if len(types) == 0 {
+		return fmt.Errorf("%w: event_types must not be empty", ErrInvalidWebhook)
+	}
+	for _, t := range types {
+		if !webhookEventTypes[t] {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, t)
+		}
+	}
+	return nil
+}
+
+func validateWebhookURL(u string) error {
+	if !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+		return fmt.Errorf("%w: url must be http(s)", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// CreateSubscription создаёт подписку. Секрет (переданный или сгенерированный)
+// возвращается только в ответе на создание.
+func (s *WebhookService) CreateSubscription(ctx context.Context, userID uint, req models.CreateWebhookRequest) (*models.WebhookSubscription, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = generateWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := &models.WebhookSubscription{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+		CreatedBy:  userID,
+	}
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return s.repo.ListSubscriptions(ctx)
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	return s.repo.GetSubscription(ctx, id)
+}
+
+func (s *WebhookService) UpdateSubscription(ctx context.Context, id uint, req models.UpdateWebhookRequest) (*models.WebhookSubscription, error) {
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+	}
+	if req.EventTypes != nil {
+		if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.UpdateSubscription(ctx, id, req)
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id uint) error {
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.repo.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(ctx, subscriptionID, limit)
+}
+
+// HandleEvent ставит событие в очередь доставки всем подходящим подпискам.
+// Подключается к NotificationService как слушатель.
+func (s *WebhookService) HandleEvent(ctx context.Context, eventType string, msg any) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to marshal event", "component", "webhook", "event_type", eventType, logging.Err(err))
+		return
+	}
+	if _, err := s.repo.EnqueueEvent(ctx, eventType, payload); err != nil {
+		slog.ErrorContext(ctx, "failed to enqueue event", "component", "webhook", "event_type", eventType, logging.Err(err))
+	}
+}
+
+// SendTestEvent сразу отправляет подписке тестовое событие (без повторов)
+// и возвращает запись журнала с результатом.
+func (s *WebhookService) SendTestEvent(ctx context.Context, subscriptionID uint) (*models.WebhookDelivery, error) {
+	sub, err := s.repo.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(map[string]any{
+		"subscription_id": sub.ID,
+		"message":         "This is a test event from ShopFlow",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.repo.CreateDelivery(ctx, sub.ID, models.WebhookEventTest, payload)
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := s.repo.ClaimDelivery(ctx, delivery.ID, s.opts.Timeout*2)
+	if err != nil {
+		return nil, err
+	}
+	s.deliver(ctx, claimed, true)
+
+	return s.repo.GetDelivery(ctx, delivery.ID)
+}
+
+// Run — воркер доставки: периодически забирает доставки, время которых пришло,
+// и отправляет их. Несколько реплик могут работать параллельно. Завершается с ctx.
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	lease := s.opts.Timeout * 2
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-purge.C:
+			if _, err := s.repo.PurgeDeliveries(ctx, s.opts.Retention); err != nil {
+				slog.Error("failed to purge deliveries", "component", "webhook", logging.Err(err))
+			}
+			continue
+		case <-ticker.C:
+		}
+
+		claimed, err := s.repo.ClaimDueDeliveries(ctx, s.opts.Concurrency, lease)
+		if err != nil {
+			slog.Error("failed to claim deliveries", "component", "webhook", logging.Err(err))
+			continue
+		}
+
+		var wg sync.WaitGroup
+		for i := range claimed {
+			wg.Add(1)
+			go func(c *repository.ClaimedDelivery) {
+				defer wg.Done()
+				s.deliver(ctx, c, false)
+			}(&claimed[i])
+		}
+		wg.Wait()
+	}
+}
+
+// SignWebhook — подпись тела вебхука для заголовка X-ShopFlow-Signature
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookService) deliver(ctx context.Context, c *repository.ClaimedDelivery, final bool) {
+	d := &c.Delivery
+	// результат попытки записываем и при остановке воркера
+	rctx := context.WithoutCancel(ctx)
+	body, err := json.Marshal(models.WebhookEvent{
+		ID:        d.ID,
+		Type:      d.EventType,
+		CreatedAt: d.CreatedAt,
+		Data:      d.Payload,
+	})
+	if err != nil {
+		s.recordFailure(rctx, d, 0, err.Error(), "", true)
+		return
+	}
+
+	ts := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		s.recordFailure(rctx, d, 0, err.Error(), "", true)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ShopFlow-Webhooks/1.0")
+	req.Header.Set(WebhookHeaderEvent, d.EventType)
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set(WebhookHeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(WebhookHeaderSignature, fmt.Sprintf("t=%d,v1=%s", ts, SignWebhook(c.Secret, ts, body)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.recordFailure(rctx, d, 0, err.Error(), "", final)
+		return
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseLog))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := s.repo.MarkDelivered(rctx, d, resp.StatusCode, string(snippet)); err != nil {
+			slog.ErrorContext(rctx, "failed to record delivery", "component", "webhook", "delivery_id", d.ID, logging.Err(err))
+		}
+		return
+	}
+	s.recordFailure(rctx, d, resp.StatusCode, fmt.Sprintf("unexpected status %d", resp.StatusCode), string(snippet), final)
+}
+
+func (s *WebhookService) recordFailure(ctx context.Context, d *models.WebhookDelivery, statusCode int, errText, response string, final bool) {
+	var next *time.Time
+	if !final && d.Attempts < s.opts.MaxAttempts {
+		t := time.Now().Add(s.backoff(d.Attempts))
+		next = &t
+	}
+	disabled, err := s.repo.MarkAttemptFailed(ctx, d, statusCode, errText, response, next, s.opts.DisableAfter)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to record failed delivery", "component", "webhook", "delivery_id", d.ID, logging.Err(err))
+		return
+	}
+	if disabled {
+		slog.WarnContext(ctx, "subscription disabled after consecutive failures", "component", "webhook",
+			"subscription_id", d.SubscriptionID, "failures", s.opts.DisableAfter)
+	}
+}
+
+// backoff — экспоненциальная задержка перед попыткой attempt+1 с джиттером ±20%,
+// не больше MaxBackoff (джиттер тоже не выводит за предел)
+func (s *WebhookService) backoff(attempt int) time.Duration {
+	jitter := 0.8 + mrand.Float64()*0.4
+	delay := float64(s.opts.BaseBackoff) * math.Pow(2, float64(attempt-1)) * jitter
+	if delay > float64(s.opts.MaxBackoff) {
+		delay = float64(s.opts.MaxBackoff)
+	}
+	return time.Duration(delay)
+}
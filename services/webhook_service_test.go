@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignWebhook(t *testing.T) {
+	// ожидаемые значения — echo -n '<ts>.<body>' | openssl dgst -sha256 -hmac <secret>
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      string
+		want      string
+	}{
+		{
+			name:      "event payload",
+			secret:    "whsec_test",
+			timestamp: 1700000000,
+			body:      `{"id":1}`,
+			want:      "2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8",
+		},
+		{
+			name:      "empty body",
+			secret:    "s",
+			timestamp: 0,
+			body:      "",
+			want:      "2572e102ebbc88d57bc0ef48471ee28bb7fc8c6e9c0558b3c8e5d276f84ac9c3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SignWebhook(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("SignWebhook = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignWebhookCoversTimestampAndSecret(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	base := SignWebhook("whsec_test", 1700000000, body)
+	if SignWebhook("whsec_test", 1700000001, body) == base {
+		t.Error("signature does not depend on timestamp")
+	}
+	if SignWebhook("whsec_other", 1700000000, body) == base {
+		t.Error("signature does not depend on secret")
+	}
+	if SignWebhook("whsec_test", 1700000000, []byte(`{"id":2}`)) == base {
+		t.Error("signature does not depend on body")
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	s := &WebhookService{opts: WebhookOptions{BaseBackoff: 30 * time.Second, MaxBackoff: time.Hour}}
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 1, min: 24 * time.Second, max: 36 * time.Second},
+		{attempt: 2, min: 48 * time.Second, max: 72 * time.Second},
+		{attempt: 5, min: 384 * time.Second, max: 576 * time.Second},
+		// 30s * 2^7 = 64m: с джиттером может выйти за предел, но ограничивается им
+		{attempt: 8, min: 3072 * time.Second, max: time.Hour},
+		{attempt: 20, min: time.Hour, max: time.Hour},
+		{attempt: 1000, min: time.Hour, max: time.Hour},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			got := s.backoff(tt.attempt)
+			if got < tt.min || got > tt.max {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", tt.attempt, got, tt.min, tt.max)
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// amqpCarrier — заголовки AMQP-сообщения как носитель контекста трассировки
+type amqpCarrier amqp.Table
+
+func (c amqpCarrier) Get(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+func (c amqpCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c amqpCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// StartPublish открывает span публикации сообщения в exchange с ключом routingKey
+func StartPublish(ctx context.Context, exchange, routingKey string) (context.Context, trace.Span) {
+	name := exchange
+	if name == "" {
+		name = routingKey // default exchange: сообщение уходит прямо в очередь routingKey
+	}
+	return Tracer().Start(ctx, "publish "+name,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitMQ,
+			semconv.MessagingOperationTypeSend,
+			semconv.MessagingDestinationName(exchange),
+			semconv.MessagingRabbitMQDestinationRoutingKey(routingKey),
+		),
+	)
+}
+
+// StartConsume открывает span обработки полученного сообщения; ctx должен содержать
+// контекст отправителя (ExtractAMQP), тогда обработка попадёт в его трассу
+func StartConsume(ctx context.Context, exchange, routingKey string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, "process "+routingKey,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitMQ,
+			semconv.MessagingOperationTypeProcess,
+			semconv.MessagingDestinationName(exchange),
+			semconv.MessagingRabbitMQDestinationRoutingKey(routingKey),
+		),
+	)
+}
+
+// InjectAMQP записывает контекст трассировки из ctx в заголовки сообщения (traceparent, tracestate)
+func InjectAMQP(ctx context.Context, headers amqp.Table) amqp.Table {
+	if headers == nil {
+		headers = amqp.Table{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, amqpCarrier(headers))
+	return headers
+}
+
+// ExtractAMQP восстанавливает контекст трассировки из заголовков полученного сообщения
+func ExtractAMQP(ctx context.Context, headers amqp.Table) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, amqpCarrier(headers))
+}
@@ -0,0 +1,33 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartQuery открывает span метода репозитория; вызывается как
+//
+//	ctx, span := tracing.StartQuery(ctx, "application", "GetAll")
+//	defer func() { tracing.EndQuery(span, err) }()
+func StartQuery(ctx context.Context, repository, method string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, repository+"."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(method),
+		),
+	)
+}
+
+// EndQuery завершает span метода репозитория. sql.ErrNoRows — штатный ответ «не найдено»,
+// а не сбой запроса, поэтому span с ним ошибкой не помечается.
+func EndQuery(span trace.Span, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	End(span, err)
+}